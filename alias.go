@@ -0,0 +1,83 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// AliasPageMode selects what is generated for the aliases of a command.
+type AliasPageMode string
+
+const (
+	// NoAliasPages generates nothing for aliases
+	NoAliasPages AliasPageMode = ""
+
+	// SoAliasPages generates a page with a .so request that includes the
+	// page of the command, the way man pages for aliases are usually made
+	SoAliasPages AliasPageMode = "so"
+
+	// SymlinkAliasPages creates a symbolic link to the page of the command
+	SymlinkAliasPages AliasPageMode = "symlink"
+)
+
+// aliasPageFiles returns the files for the aliases of cmd whose page is
+// written to name.
+func aliasPageFiles(cmd *cobra.Command, name string, opts *CobraManOptions) ([]pageFile, error) {
+	switch opts.AliasPages {
+	case NoAliasPages:
+		return nil, nil
+	case SoAliasPages:
+		if !opts.useSection {
+			return nil, fmt.Errorf("alias pages with .so requests need a man template")
+		}
+	case SymlinkAliasPages:
+	default:
+		return nil, fmt.Errorf("unknown AliasPages mode: %s", opts.AliasPages)
+	}
+
+	files := make([]pageFile, 0, len(cmd.Aliases))
+	for _, alias := range cmd.Aliases {
+		if cmd.HasParent() {
+			alias = cmd.Parent().CommandPath() + " " + alias
+		}
+		basename := strings.Replace(alias, " ", opts.fileCmdSeparator, -1)
+		aliasName := filepath.Join(filepath.Dir(name), basename+opts.fileExtension(opts.pageSection(cmd)))
+		files = append(files, pageFile{name: aliasName, cmd: cmd, target: name})
+	}
+	return files, nil
+}
+
+// soRequest returns the content of the page for the alias pf.  The path is
+// relative to the top of the man hierarchy as man expects, and names the
+// uncompressed page since man finds compressed ones by itself.
+func (opts *CobraManOptions) soRequest(pf pageFile) string {
+	target := strings.TrimSuffix(filepath.Base(pf.target), ".gz")
+	return ".so " + path.Join("man"+opts.pageSection(pf.cmd), target) + "\n"
+}
+
+// writeAliasLink replaces filename with a symbolic link to the page of the
+// alias pf.
+func writeAliasLink(filename string, pf pageFile) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(filepath.Base(pf.target), filename)
+}
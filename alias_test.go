@@ -0,0 +1,132 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func aliasTestCmd() *cobra.Command {
+	appCmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	appCmd.AddCommand(&cobra.Command{Use: "remove", Aliases: []string{"rm", "del"}, Short: "remove things", Run: func(cmd *cobra.Command, args []string) {}})
+	return appCmd
+}
+
+func TestAliases(t *testing.T) {
+	cmd := aliasTestCmd().Commands()[0]
+	page := BuildPage(cmd, &CobraManOptions{})
+	assert.Equal(t, []string{"rm", "del"}, page.Aliases)
+	assert.Equal(t, []string{"foo rm", "foo del"}, page.AliasPaths)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "troff", buf))
+	assert.Contains(t, buf.String(), ".SH NAME\nfoo\\-remove, foo\\-rm, foo\\-del - remove things\n")
+	assert.Contains(t, buf.String(), "\\fBfoo remove|rm|del \\fR")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Nm foo\\-remove ,\n.Nm foo\\-rm ,\n.Nm foo\\-del\n.Nd remove things\n")
+	assert.Contains(t, buf.String(), ".Nm foo remove | rm | del\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "markdown", buf))
+	assert.Contains(t, buf.String(), "remove things\n\nAliases: foo rm, foo del\n")
+}
+
+func TestSoAliasPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := CobraManOptions{AliasPages: SoAliasPages, SectionDirectories: true, Gzip: true, ManifestFile: "MANIFEST"}
+	docs, err := ListDocs(aliasTestCmd(), &opts, "", "troff")
+	assert.NoError(t, err)
+	assert.Equal(t, []DocFile{
+		{Path: "man1/foo.1.gz", CommandPath: "foo"},
+		{Path: "man1/foo-remove.1.gz", CommandPath: "foo remove"},
+		{Path: "man1/foo-rm.1.gz", CommandPath: "foo remove"},
+		{Path: "man1/foo-del.1.gz", CommandPath: "foo remove"},
+	}, docs)
+
+	assert.NoError(t, GenerateDocs(aliasTestCmd(), &opts, dir, "troff"))
+	content, err := readDocFile(filepath.Join(dir, "man1", "foo-rm.1.gz"), &opts)
+	assert.NoError(t, err)
+	assert.Equal(t, ".so man1/foo-remove.1\n", string(content))
+	manifest, _ := ioutil.ReadFile(filepath.Join(dir, "MANIFEST"))
+	assert.Contains(t, string(manifest), "man1/foo-del.1.gz\n")
+
+	result, err := CheckDocs(aliasTestCmd(), &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.True(t, result.IsCurrent())
+
+	// A .so request only makes sense for man pages
+	_, err = ListDocs(aliasTestCmd(), &CobraManOptions{AliasPages: SoAliasPages}, "", "markdown")
+	assert.Error(t, err)
+	_, err = ListDocs(aliasTestCmd(), &CobraManOptions{AliasPages: "copy"}, "", "troff")
+	assert.Error(t, err)
+}
+
+func TestSymlinkAliasPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := CobraManOptions{AliasPages: SymlinkAliasPages}
+	assert.NoError(t, GenerateDocs(aliasTestCmd(), &opts, dir, "markdown"))
+	// Generating again replaces the links
+	assert.NoError(t, GenerateDocs(aliasTestCmd(), &opts, dir, "markdown"))
+	link, err := os.Readlink(filepath.Join(dir, "foo_rm.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo_remove.md", link)
+
+	result, err := CheckDocs(aliasTestCmd(), &opts, dir, "markdown")
+	assert.NoError(t, err)
+	assert.True(t, result.IsCurrent())
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "foo_del.md")))
+	assert.NoError(t, os.Symlink("foo.md", filepath.Join(dir, "foo_rm.md.tmp")))
+	assert.NoError(t, os.Rename(filepath.Join(dir, "foo_rm.md.tmp"), filepath.Join(dir, "foo_rm.md")))
+	result, err = CheckDocs(aliasTestCmd(), &opts, dir, "markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "foo_rm.md")}, result.Stale)
+	assert.Equal(t, []string{filepath.Join(dir, "foo_del.md")}, result.Missing)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateArchive(aliasTestCmd(), &opts, buf, TarGzArchive, "markdown"))
+	zr, err := gzip.NewReader(buf)
+	assert.NoError(t, err)
+	tr := tar.NewReader(zr)
+	links := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if hdr.Typeflag == tar.TypeSymlink {
+			links[hdr.Name] = hdr.Linkname
+		}
+	}
+	assert.Equal(t, map[string]string{"foo_rm.md": "foo_remove.md", "foo_del.md": "foo_remove.md"}, links)
+}
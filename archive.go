@@ -0,0 +1,218 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ArchiveFormat selects the kind of archive GenerateArchive writes.
+type ArchiveFormat int
+
+const (
+	// TarGzArchive is a tar file compressed with gzip
+	TarGzArchive ArchiveFormat = iota + 1
+
+	// ZipArchive is a zip file
+	ZipArchive
+)
+
+// ArchiveFormatFor returns the ArchiveFormat that matches the extension of
+// filename: .tar.gz, .tgz or .zip.
+func ArchiveFormatFor(filename string) (ArchiveFormat, error) {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGzArchive, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return ZipArchive, nil
+	}
+	return 0, fmt.Errorf("unknown archive format: %s", filename)
+}
+
+// archiveTime is the modification time of every entry in an archive.  It is
+// the earliest time a zip file can hold.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type archiveEntry struct {
+	name    string // uses forward slashes, ends with one for directories
+	content []byte
+	link    string // target of a symbolic link
+}
+
+// GenerateArchive generates the same files as GenerateDocs for the passed
+// in cobra.Command and all of its children but writes them into an archive
+// on w instead of a directory.  The archive is reproducible: the entries
+// are sorted by name, every entry has the same modification time, files
+// have mode 0644, directories 0755 and symbolic links 0777.  The ManifestFile, if set, is added
+// to the archive.  Prune can't be used with an archive.
+func GenerateArchive(cmd *cobra.Command, opts *CobraManOptions, w io.Writer, format ArchiveFormat, templateName string) error {
+	// Set defaults
+	validate(opts, templateName)
+
+	if opts.Prune {
+		return fmt.Errorf("generated files can't be pruned from an archive")
+	}
+	if format != TarGzArchive && format != ZipArchive {
+		return fmt.Errorf("unknown archive format: %d", format)
+	}
+
+	files, err := pageFiles(cmd, opts)
+	if err != nil {
+		return err
+	}
+	contents := make([][]byte, len(files))
+	err = forEachPage(files, opts, func(n int, opts *CobraManOptions) error {
+		buf := new(bytes.Buffer)
+		if files[n].target != "" && opts.AliasPages == SymlinkAliasPages {
+			return nil
+		}
+		if err := generateDocFile(buf, files[n], opts, templateName); err != nil {
+			return err
+		}
+		contents[n] = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	entries := make([]archiveEntry, 0, len(files)+1)
+	for n, pf := range files {
+		entry := archiveEntry{name: filepath.ToSlash(pf.name), content: contents[n]}
+		if pf.target != "" && opts.AliasPages == SymlinkAliasPages {
+			entry.link = path.Base(filepath.ToSlash(pf.target))
+		}
+		entries = append(entries, entry)
+	}
+	if opts.ManifestFile != "" {
+		entries = append(entries, archiveEntry{name: filepath.ToSlash(opts.ManifestFile), content: manifestContent(files)})
+	}
+	entries = addArchiveDirectories(entries)
+
+	if format == ZipArchive {
+		return writeZip(w, entries)
+	}
+	return writeTarGz(w, entries)
+}
+
+// generateArchiveFile writes the archive GenerateArchive generates to
+// filename, choosing the format by its extension.
+func generateArchiveFile(cmd *cobra.Command, opts *CobraManOptions, filename string, templateName string) error {
+	format, err := ArchiveFormatFor(filename)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := GenerateArchive(cmd, opts, f, format, templateName); err != nil {
+		f.Close()
+		os.Remove(filename)
+		return err
+	}
+	return f.Close()
+}
+
+// addArchiveDirectories adds an entry for every directory the entries are
+// in and sorts them all by name, which places each directory before its
+// content.
+func addArchiveDirectories(entries []archiveEntry) []archiveEntry {
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		for dir := path.Dir(entry.name); dir != "." && dir != "/" && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			entries = append(entries, archiveEntry{name: dir + "/"})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
+
+func writeTarGz(w io.Writer, entries []archiveEntry) error {
+	// The header's Name and ModTime are left unset to keep the output reproducible
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	for _, entry := range entries {
+		hdr := &tar.Header{
+			Name:     entry.name,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+			ModTime:  archiveTime,
+			Typeflag: tar.TypeReg,
+		}
+		if strings.HasSuffix(entry.name, "/") {
+			hdr.Mode = 0755
+			hdr.Typeflag = tar.TypeDir
+		} else if entry.link != "" {
+			hdr.Mode = 0777
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = entry.link
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZip(w io.Writer, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		hdr := &zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Deflate,
+			Modified: archiveTime,
+		}
+		hdr.SetMode(0644)
+		content := entry.content
+		if strings.HasSuffix(entry.name, "/") {
+			hdr.Method = zip.Store
+			hdr.SetMode(os.ModeDir | 0755)
+		} else if entry.link != "" {
+			// A symbolic link holds the name of its target
+			hdr.Method = zip.Store
+			hdr.SetMode(os.ModeSymlink | 0777)
+			content = []byte(entry.link)
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
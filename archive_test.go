@@ -0,0 +1,126 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func archiveTestCmd() *cobra.Command {
+	appCmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	appCmd.AddCommand(&cobra.Command{Use: "zap", Short: "zap things", Run: func(cmd *cobra.Command, args []string) {}})
+	appCmd.AddCommand(&cobra.Command{Use: "bar", Short: "bar things", Run: func(cmd *cobra.Command, args []string) {}})
+	return appCmd
+}
+
+func TestArchiveFormatFor(t *testing.T) {
+	for name, format := range map[string]ArchiveFormat{"docs.tar.gz": TarGzArchive, "DOCS.TGZ": TarGzArchive, "d/docs.zip": ZipArchive} {
+		got, err := ArchiveFormatFor(name)
+		assert.NoError(t, err)
+		assert.Equal(t, format, got, name)
+	}
+	_, err := ArchiveFormatFor("docs.tar")
+	assert.Error(t, err)
+}
+
+func TestGenerateTarGzArchive(t *testing.T) {
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date, SectionDirectories: true, ManifestFile: "MANIFEST"}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateArchive(archiveTestCmd(), &opts, buf, TarGzArchive, "troff"))
+
+	// The same options give the same bytes
+	buf2 := new(bytes.Buffer)
+	assert.NoError(t, GenerateArchive(archiveTestCmd(), &opts, buf2, TarGzArchive, "troff"))
+	assert.Equal(t, buf.Bytes(), buf2.Bytes())
+
+	zr, err := gzip.NewReader(buf)
+	assert.NoError(t, err)
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, hdr.Name)
+		assert.True(t, archiveTime.Equal(hdr.ModTime), hdr.Name)
+		if hdr.Typeflag == tar.TypeDir {
+			assert.Equal(t, int64(0755), hdr.Mode, hdr.Name)
+			continue
+		}
+		assert.Equal(t, int64(0644), hdr.Mode, hdr.Name)
+		content, _ := ioutil.ReadAll(tr)
+		if hdr.Name == "man1/foo-zap.1" {
+			page := new(bytes.Buffer)
+			assert.NoError(t, GenerateOnePage(archiveTestCmd().Commands()[1], &opts, "troff", page))
+			assert.Equal(t, page.String(), string(content))
+		}
+	}
+	assert.Equal(t, []string{"MANIFEST", "man1/", "man1/foo-bar.1", "man1/foo-zap.1", "man1/foo.1"}, names)
+
+	opts.Prune = true
+	assert.Error(t, GenerateArchive(archiveTestCmd(), &opts, buf, TarGzArchive, "troff"))
+}
+
+func TestGenerateZipArchive(t *testing.T) {
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date, Gzip: true, Parallelism: 2}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateArchive(archiveTestCmd(), &opts, buf, ZipArchive, "markdown"))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		assert.Equal(t, os.FileMode(0644), f.Mode(), f.Name)
+		assert.True(t, archiveTime.Equal(f.Modified), f.Name)
+	}
+	assert.Equal(t, []string{"foo.md.gz", "foo_bar.md.gz", "foo_zap.md.gz"}, names)
+}
+
+func TestArchiveFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dg := CreateDocGenCmdLineTool(archiveTestCmd())
+	dg.AddDocGenerator(&CobraManOptions{}, "troff")
+	archive := filepath.Join(dir, "docs.zip")
+	dg.docCmd.SetArgs([]string{"generate-troff", "--archive", archive, "--directory", dir})
+	assert.NoError(t, dg.Execute())
+	zr, err := zip.OpenReader(archive)
+	if assert.NoError(t, err) {
+		assert.Len(t, zr.File, 3)
+		zr.Close()
+	}
+	checkFileNotExist(t, filepath.Join(dir, "foo.1"))
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--archive", filepath.Join(dir, "docs.rar")})
+	assert.Error(t, dg.Execute())
+}
@@ -0,0 +1,379 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxProbedArgs is the most arguments tried when working out the counts a
+// cobra validator accepts.  A validator that accepts this many is taken
+// to have no maximum.
+const maxProbedArgs = 32
+
+// ManArgs is the model of the positional arguments of a command.
+type ManArgs struct {
+	// Validator is the name of the cobra validator of the command (e.g.
+	// "ExactArgs").  It is empty if the command has no validator or one
+	// that is not from cobra.
+	Validator string
+
+	// Min and Max are the number of arguments the command accepts.  Max
+	// is -1 if there is no maximum.
+	Min int
+	Max int
+
+	// OnlyValid is true if the arguments must be among the ValidArgs of
+	// the command.  It is worked out by running the cobra validator, so it
+	// is only set for commands with ValidArgs.
+	OnlyValid bool
+
+	// ValidArgs are the values shell completion offers for the first
+	// argument and ArgAliases the other values it accepts.  Completion
+	// descriptions (the text after a tab) are left out.
+	ValidArgs  []string
+	ArgAliases []string
+
+	// Positions describes the arguments in the order they are given.
+	// Optional ones always come after the required ones.
+	Positions []ArgPosition
+
+	// Declared is true if the arguments were declared with the "man-args"
+	// annotation, in which case Positions holds the declarations
+	Declared bool
+
+	// Synopsis is the arguments as they are shown in a synopsis (e.g.
+	// "<src> <dst>" or "<file>...")
+	Synopsis string
+}
+
+// ArgPosition is the model of one positional argument.
+type ArgPosition struct {
+	Name        string
+	Description string
+
+	// Optional is true if the argument may be left out, which leaves out
+	// the optional arguments after it too
+	Optional bool
+
+	// Repeated is true if the argument may be given any number of times
+	Repeated bool
+
+	// Choices are the ValidArgs of the command if the argument is one of
+	// them
+	Choices []string
+}
+
+// AnnotateArgs declares the positional arguments of cmd in its "man-args"
+// annotation, replacing any that were declared before.  Arguments after an
+// optional one are optional too.
+func AnnotateArgs(cmd *cobra.Command, args ...ArgPosition) {
+	lines := make([]string, 0, len(args))
+	for _, arg := range args {
+		name := arg.Name
+		if arg.Repeated {
+			name += "..."
+		}
+		if arg.Optional {
+			name = "[" + name + "]"
+		}
+		lines = append(lines, name+": "+strings.Join(strings.Fields(arg.Description), " "))
+	}
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations["man-args"] = strings.Join(lines, "\n")
+}
+
+// parseArgDeclarations parses the value of the "man-args" annotation.  Each
+// line declares an argument as "name: description", where the name is in
+// brackets for an optional argument and ends with "..." for a repeated one
+// (e.g. "[file...]: files to read").
+func parseArgDeclarations(declarations string) []ArgPosition {
+	var positions []ArgPosition
+	optional := false
+	for _, line := range strings.Split(declarations, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		arg := ArgPosition{Name: strings.TrimSpace(parts[0])}
+		if len(parts) == 2 {
+			arg.Description = strings.TrimSpace(parts[1])
+		}
+		if strings.HasPrefix(arg.Name, "[") && strings.HasSuffix(arg.Name, "]") {
+			arg.Name = strings.TrimSpace(arg.Name[1 : len(arg.Name)-1])
+			optional = true
+		}
+		arg.Optional = optional
+		if strings.HasSuffix(arg.Name, "...") {
+			arg.Name = strings.TrimSpace(strings.TrimSuffix(arg.Name, "..."))
+			arg.Repeated = true
+		}
+		positions = append(positions, arg)
+	}
+	return positions
+}
+
+// buildArgs returns the model of the positional arguments of cmd.  The
+// counts come from the cobra validator the command uses, or from the
+// "man-arg-count" annotation if it is set.  The arguments are described by
+// the "man-args" annotation, or else named by the "man-arg-names" one.
+func buildArgs(cmd *cobra.Command) ManArgs {
+	args := ManArgs{Max: -1}
+
+	if cmd.Args != nil {
+		funcName := runtime.FuncForPC(reflect.ValueOf(cmd.Args).Pointer()).Name()
+		if strings.HasPrefix(funcName, "github.com/spf13/cobra.") {
+			// Validators that take counts return a closure named like
+			// "cobra.ExactArgs.func1"
+			args.Validator = strings.Split(strings.TrimPrefix(funcName, "github.com/spf13/cobra."), ".")[0]
+		}
+	}
+
+	// The annotation is read first so validators are only run when nothing
+	// else gives the counts
+	count, hasCount := cmd.Annotations["man-arg-count"]
+	if hasCount {
+		var min, max int
+		if min, max, hasCount = parseArgCount(count); hasCount {
+			args.Min, args.Max = min, max
+		}
+	}
+	if !hasCount {
+		args.setCounts(cmd.Args)
+	}
+
+	if declarations := parseArgDeclarations(cmd.Annotations["man-args"]); len(declarations) > 0 {
+		args.Positions = declarations
+		args.Declared = true
+		if args.Validator == "" && !hasCount {
+			// Nothing else tells how many arguments there are
+			args.Min, args.Max = 0, 0
+			for _, arg := range declarations {
+				if !arg.Optional {
+					args.Min++
+				}
+				if arg.Repeated {
+					args.Max = -1
+				} else if args.Max >= 0 {
+					args.Max++
+				}
+			}
+		}
+	} else {
+		args.Positions = argPositions(strings.Fields(cmd.Annotations["man-arg-names"]), args.Min, args.Max)
+	}
+
+	args.ValidArgs = stripCompletionDescriptions(cmd.ValidArgs)
+	args.ArgAliases = cmd.ArgAliases
+	if len(args.ValidArgs) > 0 {
+		if args.Validator != "" {
+			args.OnlyValid = probeOnlyValid(cmd.Args, args.ValidArgs, args.Min, args.Max)
+		}
+		// Completion only offers ValidArgs for the first argument, but
+		// OnlyValidArgs checks all of them
+		for i := range args.Positions {
+			if i == 0 || args.OnlyValid {
+				args.Positions[i].Choices = args.ValidArgs
+			}
+		}
+	}
+	args.Synopsis = argSynopsis(args.Positions)
+	return args
+}
+
+// setCounts sets the counts of arguments from the validator named by
+// args.Validator.
+func (args *ManArgs) setCounts(validator cobra.PositionalArgs) {
+	switch args.Validator {
+	case "NoArgs":
+		args.Max = 0
+	case "ExactArgs", "ExactValidArgs", "MinimumNArgs", "MaximumNArgs", "RangeArgs":
+		args.Min, args.Max = probeArgs(validator)
+	case "MatchAll":
+		// Probing calls the validators MatchAll was given many times, and
+		// they may be the application's own code with side effects.  Set
+		// "man-arg-count" to skip it.  The counts stay unknown if one of
+		// them panics.
+		args.Min, args.Max = probeArgs(validator)
+	}
+}
+
+// stripCompletionDescriptions returns values without the descriptions that
+// follow a tab for shell completion (e.g. "start\tStart the service").
+func stripCompletionDescriptions(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	stripped := make([]string, len(values))
+	for i, value := range values {
+		stripped[i] = strings.SplitN(value, "\t", 2)[0]
+	}
+	return stripped
+}
+
+// probeArgs returns the counts of arguments validator accepts by calling it
+// with ever more arguments.  It is meant for cobra's validators, which have
+// no side effects.  A validator that panics gives no counts.
+func probeArgs(validator cobra.PositionalArgs) (min int, max int) {
+	defer func() {
+		if recover() != nil {
+			min, max = 0, -1
+		}
+	}()
+	probe := &cobra.Command{}
+	min, max = -1, -1
+	for n := 0; n <= maxProbedArgs; n++ {
+		if validator(probe, make([]string, n)) != nil {
+			continue
+		}
+		if min < 0 {
+			min = n
+		}
+		max = n
+	}
+	if min < 0 {
+		return 0, -1
+	}
+	if max == maxProbedArgs {
+		max = -1
+	}
+	return min, max
+}
+
+// probeOnlyValid reports whether validator rejects arguments that are not
+// in validArgs, as cobra.OnlyValidArgs does.  This is worked out by calling
+// it once with valid arguments and once with an invalid one, since
+// validators like cobra.MatchAll can't be told apart by name.  A validator
+// that panics is taken not to check the arguments.
+func probeOnlyValid(validator cobra.PositionalArgs, validArgs []string, min int, max int) (onlyValid bool) {
+	if max == 0 {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			onlyValid = false
+		}
+	}()
+	probe := &cobra.Command{ValidArgs: validArgs}
+	count := min
+	if count < 1 {
+		count = 1
+	}
+	args := make([]string, count)
+	for i := range args {
+		args[i] = validArgs[0]
+	}
+	if validator(probe, args) != nil {
+		return false
+	}
+	// No valid argument can hold a tab as the descriptions are stripped
+	args[count-1] = validArgs[0] + "\tinvalid"
+	return validator(probe, args) != nil
+}
+
+// parseArgCount parses the value of the "man-arg-count" annotation, which
+// is a count (e.g. "2"), a range (e.g. "1-3") or a minimum (e.g. "1-").
+func parseArgCount(count string) (min int, max int, ok bool) {
+	parts := strings.SplitN(strings.TrimSpace(count), "-", 2)
+	min, err := strconv.Atoi(parts[0])
+	if err != nil || min < 0 {
+		return 0, 0, false
+	}
+	if len(parts) == 1 {
+		return min, min, true
+	}
+	if parts[1] == "" {
+		return min, -1, true
+	}
+	max, err = strconv.Atoi(parts[1])
+	if err != nil || max < min {
+		return 0, 0, false
+	}
+	return min, max, true
+}
+
+// argPositions returns the positions for min to max arguments.  A position
+// without a name gets the last name, or "arg" if there are no names.
+func argPositions(names []string, min int, max int) []ArgPosition {
+	if max == 0 {
+		return nil
+	}
+	if max < 0 && min == 0 && len(names) == 0 {
+		// Any number of arguments is shown the way it always was
+		return []ArgPosition{{Name: "args", Optional: true}}
+	}
+
+	count := max
+	if max < 0 {
+		count = len(names)
+		if count < min {
+			count = min
+		}
+		if count == 0 {
+			count = 1
+		}
+	}
+	positions := make([]ArgPosition, count)
+	for i := range positions {
+		name := "arg"
+		if i < len(names) {
+			name = names[i]
+		} else if len(names) > 0 {
+			name = names[len(names)-1]
+		}
+		positions[i] = ArgPosition{Name: name, Optional: i >= min}
+	}
+	positions[count-1].Repeated = max < 0
+	return positions
+}
+
+// argSynopsis returns positions as they are shown in a synopsis.  Each
+// optional argument is nested in the brackets of the one before it.  An
+// argument with choices is shown as a group such as "{start|stop}".
+func argSynopsis(positions []ArgPosition) string {
+	words := make([]string, 0, len(positions))
+	optional := ""
+	for i := len(positions) - 1; i >= 0; i-- {
+		word := "<" + positions[i].Name + ">"
+		if choices := positions[i].Choices; len(choices) > 0 {
+			word = strings.Join(choices, "|")
+			if !positions[i].Optional {
+				word = "{" + word + "}"
+			}
+		}
+		if positions[i].Repeated {
+			word += "..."
+		}
+		if !positions[i].Optional {
+			words = append([]string{word}, words...)
+			continue
+		}
+		if optional != "" {
+			word += " " + optional
+		}
+		optional = "[" + word + "]"
+	}
+	if optional != "" {
+		words = append(words, optional)
+	}
+	return strings.Join(words, " ")
+}
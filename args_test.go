@@ -0,0 +1,214 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildArgs(t *testing.T) {
+	custom := func(cmd *cobra.Command, args []string) error { return errors.New("never") }
+	tests := []struct {
+		validator   cobra.PositionalArgs
+		annotations map[string]string
+		name        string
+		min, max    int
+		synopsis    string
+	}{
+		{nil, nil, "", 0, -1, "[<args>]"},
+		{cobra.NoArgs, nil, "NoArgs", 0, 0, ""},
+		{cobra.ArbitraryArgs, nil, "ArbitraryArgs", 0, -1, "[<args>]"},
+		{cobra.OnlyValidArgs, nil, "OnlyValidArgs", 0, -1, "[<args>]"},
+		{cobra.ExactArgs(2), map[string]string{"man-arg-names": "src dst"}, "ExactArgs", 2, 2, "<src> <dst>"},
+		{cobra.MinimumNArgs(1), map[string]string{"man-arg-names": "file"}, "MinimumNArgs", 1, -1, "<file>..."},
+		{cobra.MinimumNArgs(0), map[string]string{"man-arg-names": "file"}, "MinimumNArgs", 0, -1, "[<file>...]"},
+		{cobra.MaximumNArgs(2), nil, "MaximumNArgs", 0, 2, "[<arg> [<arg>]]"},
+		{cobra.RangeArgs(1, 3), map[string]string{"man-arg-names": "src dst"}, "RangeArgs", 1, 3, "<src> [<dst> [<dst>]]"},
+		{cobra.MinimumNArgs(1), map[string]string{"man-arg-names": "name value"}, "MinimumNArgs", 1, -1, "<name> [<value>...]"},
+		{custom, map[string]string{"man-arg-count": "2"}, "", 2, 2, "<arg> <arg>"},
+		{custom, map[string]string{"man-arg-count": "1-", "man-arg-names": "path"}, "", 1, -1, "<path>..."},
+		{cobra.ExactArgs(1), map[string]string{"man-arg-count": "1-2"}, "ExactArgs", 1, 2, "<arg> [<arg>]"},
+		{cobra.ExactArgs(1), map[string]string{"man-arg-count": "two"}, "ExactArgs", 1, 1, "<arg>"},
+	}
+	for _, test := range tests {
+		cmd := &cobra.Command{Use: "foo", Args: test.validator, Annotations: test.annotations}
+		args := buildArgs(cmd)
+		assert.Equal(t, test.name, args.Validator, test.synopsis)
+		assert.Equal(t, test.min, args.Min, test.synopsis)
+		assert.Equal(t, test.max, args.Max, test.synopsis)
+		assert.Equal(t, test.synopsis, args.Synopsis)
+	}
+
+	// cobra 1.6 and later implement ExactValidArgs with MatchAll
+	args := buildArgs(&cobra.Command{Use: "foo", Args: cobra.ExactValidArgs(1), ValidArgs: []string{"start", "stop"}})
+	assert.Contains(t, []string{"ExactValidArgs", "MatchAll"}, args.Validator)
+	assert.Equal(t, 1, args.Min)
+	assert.Equal(t, 1, args.Max)
+	assert.True(t, args.OnlyValid)
+	assert.Equal(t, "{start|stop}", args.Synopsis)
+	assert.False(t, buildArgs(&cobra.Command{Use: "foo", Args: cobra.ExactArgs(1), ValidArgs: []string{"start", "stop"}}).OnlyValid)
+	assert.True(t, BuildPage(&cobra.Command{Use: "foo", Args: cobra.ExactArgs(0)}, &CobraManOptions{}).NoArgs)
+}
+
+func TestArgsSynopsis(t *testing.T) {
+	cmd := &cobra.Command{Use: "cp", Args: cobra.RangeArgs(1, 2), Annotations: map[string]string{"man-arg-names": "src dst"},
+		Run: func(cmd *cobra.Command, args []string) {}}
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "troff", buf))
+	assert.Contains(t, buf.String(), "\\fBcp \\fR<src> [<dst>]\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Nm cp\n.Ar src\n.Op Ar dst\n.Ek\n")
+
+	buf.Reset()
+	cmd.Flags().Bool("force", false, "overwrite")
+	cmd.Args = cobra.MinimumNArgs(0)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Op Fl \\-force\n.Op Ar src Op Ar dst ...\n.Ek\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "markdown", buf))
+	assert.Contains(t, buf.String(), "### Synopsis\n\n`cp [flags] [<src> [<dst>...]]`\n")
+}
+
+func TestArgDeclarations(t *testing.T) {
+	cmd := &cobra.Command{Use: "cp", Run: func(cmd *cobra.Command, args []string) {}}
+	AnnotateArgs(cmd,
+		ArgPosition{Name: "src", Description: "the file\nto copy"},
+		ArgPosition{Name: "dst", Description: "where to copy it", Optional: true},
+		ArgPosition{Name: "more", Description: "more places", Repeated: true},
+	)
+	assert.Equal(t, "src: the file to copy\n[dst]: where to copy it\nmore...: more places", cmd.Annotations["man-args"])
+
+	args := buildArgs(cmd)
+	assert.True(t, args.Declared)
+	assert.Equal(t, []ArgPosition{
+		{Name: "src", Description: "the file to copy"},
+		{Name: "dst", Description: "where to copy it", Optional: true},
+		{Name: "more", Description: "more places", Optional: true, Repeated: true},
+	}, args.Positions)
+	assert.Equal(t, 1, args.Min)
+	assert.Equal(t, -1, args.Max)
+	assert.Equal(t, "<src> [<dst> [<more>...]]", args.Synopsis)
+
+	// The validator still gives the counts
+	cmd.Args = cobra.RangeArgs(1, 2)
+	args = buildArgs(cmd)
+	assert.Equal(t, 2, args.Max)
+
+	cmd.Annotations["man-args"] = "file...: files to read"
+	cmd.Args = nil
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "troff", buf))
+	assert.Contains(t, buf.String(), "\\fBcp \\fR<file>...\n")
+	assert.Contains(t, buf.String(), ".SH ARGUMENTS\n.TP\n\\fIfile\\fP ...\nfiles to read\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Nm cp\n.Ar file ...\n.Ek\n")
+	assert.Contains(t, buf.String(), ".Sh ARGUMENTS\n.Bl -tag -width Ds\n.It Ar file ...\nfiles to read\n.El\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "markdown", buf))
+	assert.Contains(t, buf.String(), "`cp <file>...`\n")
+	assert.Contains(t, buf.String(), "### Arguments\n\n* `<file>...` - files to read\n")
+}
+
+func TestValidArgs(t *testing.T) {
+	cmd := &cobra.Command{Use: "service", Args: cobra.ExactValidArgs(1), ValidArgs: []string{"start\tStart it", "stop", "restart"},
+		ArgAliases: []string{"halt"}, Run: func(cmd *cobra.Command, args []string) {}}
+	args := buildArgs(cmd)
+	assert.Equal(t, []string{"start", "stop", "restart"}, args.ValidArgs)
+	assert.Equal(t, []string{"halt"}, args.ArgAliases)
+	assert.Equal(t, "{start|stop|restart}", args.Synopsis)
+
+	// Completion only offers ValidArgs for the first argument
+	cmd.Args = cobra.RangeArgs(1, 2)
+	assert.Equal(t, "{start|stop|restart} [<arg>]", buildArgs(cmd).Synopsis)
+	cmd.Args = cobra.OnlyValidArgs
+	assert.Equal(t, "[start|stop|restart]", buildArgs(cmd).Synopsis)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "troff", buf))
+	assert.Contains(t, buf.String(), "\\fBservice \\fR[start|stop|restart]\n")
+	assert.Contains(t, buf.String(), ".PP\nValid arguments: \\fBstart\\fP, \\fBstop\\fP, \\fBrestart\\fP\n.PP\nAlso accepted: \\fBhalt\\fP\n")
+
+	buf.Reset()
+	cmd.Args = cobra.RangeArgs(1, 2)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Nm service\n.Brq Cm start | stop | restart\n.Op Ar arg\n.Ek\n")
+	assert.Contains(t, buf.String(), ".Pp\nValid arguments are\n.Cm start , stop , restart .\n.Pp\nAlso accepted are\n.Cm halt .\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "markdown", buf))
+	assert.Contains(t, buf.String(), "`service {start|stop|restart} [<arg>]`\n")
+	assert.Contains(t, buf.String(), "Valid arguments: `start`, `stop`, `restart`\n\nAlso accepted: `halt`\n")
+}
+
+func TestArgsSynopsisMdocNesting(t *testing.T) {
+	// The declarations make the first argument optional although the
+	// validator requires one
+	cmd := &cobra.Command{Use: "cat", Args: cobra.MinimumNArgs(1), Annotations: map[string]string{"man-args": "[file...]: files to read"},
+		Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.Flags().Bool("force", false, "overwrite")
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Op Fl \\-force\n.Op Ar file ...\n.Ek\n")
+}
+
+func TestMatchAll(t *testing.T) {
+	cmd := &cobra.Command{Use: "service", Args: cobra.MatchAll(cobra.RangeArgs(1, 2), cobra.OnlyValidArgs),
+		ValidArgs: []string{"start", "stop"}, Annotations: map[string]string{"man-arg-names": "action"}}
+	args := buildArgs(cmd)
+	assert.Equal(t, "MatchAll", args.Validator)
+	assert.Equal(t, 1, args.Min)
+	assert.Equal(t, 2, args.Max)
+	assert.True(t, args.OnlyValid)
+	if assert.Len(t, args.Positions, 2) {
+		assert.Equal(t, []string{"start", "stop"}, args.Positions[0].Choices)
+		assert.Equal(t, []string{"start", "stop"}, args.Positions[1].Choices)
+	}
+	assert.Equal(t, "{start|stop} [start|stop]", args.Synopsis)
+
+	// The annotation keeps the application's validators from being run
+	calls := 0
+	counting := func(cmd *cobra.Command, a []string) error {
+		calls++
+		return nil
+	}
+	cmd = &cobra.Command{Use: "foo", Args: cobra.MatchAll(counting, cobra.RangeArgs(1, 2)),
+		Annotations: map[string]string{"man-arg-count": "1-2"}}
+	args = buildArgs(cmd)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, 1, args.Min)
+	assert.Equal(t, 2, args.Max)
+
+	// A validator that doesn't expect the probe leaves the counts unknown
+	cmd = &cobra.Command{Use: "foo", Args: cobra.MatchAll(func(cmd *cobra.Command, a []string) error {
+		if cmd.Parent().Name() != "app" {
+			return errors.New("not app")
+		}
+		return nil
+	})}
+	args = buildArgs(cmd)
+	assert.Equal(t, 0, args.Min)
+	assert.Equal(t, -1, args.Max)
+}
@@ -0,0 +1,167 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// DocCheckResult lists the differences CheckDocs found between the
+// documentation that would be generated and the files in a directory.
+type DocCheckResult struct {
+	// Stale files exist but their content differs from what would be generated
+	Stale []string
+
+	// Missing files would be generated but do not exist
+	Missing []string
+
+	// Extra files were generated before, according to the manifest or
+	// else their names, but no command generates them now
+	Extra []string
+}
+
+// IsCurrent returns true if the directory matches the generated docs.
+func (r *DocCheckResult) IsCurrent() bool {
+	return len(r.Stale) == 0 && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// CheckDocs renders the documentation for the passed in cobra.Command and
+// all of its children into memory and compares it with the files in
+// directory.  Nothing is written.  It is meant to catch docs that were not
+// regenerated after a command changed, so set opts.Date to keep the pages
+// from depending on the current date.
+//
+// A file in directory is considered Extra if no command generates it but
+// it is listed in the opts.ManifestFile of an earlier run.  Without a
+// manifest a file is considered Extra if it has the same extension and
+// name prefix as the generated files.
+func CheckDocs(cmd *cobra.Command, opts *CobraManOptions, directory string, templateName string) (*DocCheckResult, error) {
+	// Set defaults
+	validate(opts, templateName)
+	if directory == "" {
+		directory = "."
+	}
+
+	files, err := pageFiles(cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DocCheckResult{}
+	generated := make(map[string]bool)
+	buf := new(bytes.Buffer)
+	for _, pf := range files {
+		generated[pf.name] = true
+		filename := filepath.Join(directory, pf.name)
+
+		if pf.target != "" && opts.AliasPages == SymlinkAliasPages {
+			link, err := os.Readlink(filename)
+			if os.IsNotExist(err) {
+				result.Missing = append(result.Missing, filename)
+			} else if err != nil || link != filepath.Base(pf.target) {
+				result.Stale = append(result.Stale, filename)
+			}
+			continue
+		}
+
+		buf.Reset()
+		if err := generatePage(buf, pf, opts, templateName); err != nil {
+			return nil, err
+		}
+
+		existing, err := readDocFile(filename, opts)
+		if os.IsNotExist(err) {
+			result.Missing = append(result.Missing, filename)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(existing, buf.Bytes()) {
+			result.Stale = append(result.Stale, filename)
+		}
+	}
+
+	// Look for files that we would have generated for commands that no longer exist
+	var previous []string
+	if opts.ManifestFile != "" {
+		if previous, err = readManifest(filepath.Join(directory, opts.ManifestFile)); err != nil {
+			return nil, err
+		}
+	}
+	if previous != nil {
+		result.Extra = extraManifestFiles(directory, previous, generated)
+	} else if result.Extra, err = extraNamedFiles(cmd, opts, directory, files, generated); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(result.Stale)
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	return result, nil
+}
+
+// extraManifestFiles returns the files listed in the previous manifest that
+// still exist in directory but are no longer generated.
+func extraManifestFiles(directory string, previous []string, generated map[string]bool) []string {
+	var extra []string
+	for _, name := range previous {
+		name = filepath.Clean(name)
+		if generated[name] || outsideDirectory(name) {
+			continue
+		}
+		filename := filepath.Join(directory, name)
+		if _, err := os.Lstat(filename); err == nil {
+			extra = append(extra, filename)
+		}
+	}
+	return extra
+}
+
+// extraNamedFiles returns the files next to the generated ones that have
+// the same extension and name prefix but are no longer generated.
+func extraNamedFiles(cmd *cobra.Command, opts *CobraManOptions, directory string, files []pageFile, generated map[string]bool) ([]string, error) {
+	var extra []string
+	basename := strings.Replace(cmd.CommandPath(), " ", opts.fileCmdSeparator, -1)
+	scanned := make(map[string]bool)
+	for _, pf := range files {
+		subdir := filepath.Dir(pf.name)
+		suffix := opts.fileExtension(opts.pageSection(pf.cmd))
+		if scanned[subdir+suffix] {
+			continue
+		}
+		scanned[subdir+suffix] = true
+
+		entries, err := ioutil.ReadDir(filepath.Join(directory, subdir))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := filepath.Join(subdir, entry.Name())
+			if entry.IsDir() || generated[name] || !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			if strings.HasPrefix(entry.Name(), basename+opts.fileCmdSeparator) {
+				extra = append(extra, filepath.Join(directory, name))
+			}
+		}
+	}
+	return extra, nil
+}
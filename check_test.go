@@ -0,0 +1,117 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkTestCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd3 := &cobra.Command{Use: "cat", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.AddCommand(cmd2, cmd3)
+	return cmd
+}
+
+func TestCheckDocs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date}
+	cmd := checkTestCmd()
+
+	// Nothing generated yet
+	result, err := CheckDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.False(t, result.IsCurrent())
+	assert.Len(t, result.Missing, 3)
+
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	result, err = CheckDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.True(t, result.IsCurrent())
+
+	// A changed command makes its page stale
+	cmd.Commands()[0].Short = "now with a description"
+	result, err = CheckDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "foo-bar.1")}, result.Stale)
+	assert.Empty(t, result.Missing)
+	assert.Empty(t, result.Extra)
+
+	// A removed command leaves an extra page behind but unrelated files are ignored
+	cmd = checkTestCmd()
+	cmd.RemoveCommand(cmd.Commands()[1])
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.1"), []byte("not ours"), 0644))
+	result, err = CheckDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	// The SEE ALSO sections of the remaining pages changed as well
+	assert.Equal(t, []string{filepath.Join(dir, "foo-bar.1"), filepath.Join(dir, "foo.1")}, result.Stale)
+	assert.Empty(t, result.Missing)
+	assert.Equal(t, []string{filepath.Join(dir, "foo-cat.1")}, result.Extra)
+}
+
+func TestCheckDocsSectionDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date, SectionDirectories: true}
+	cmd := checkTestCmd()
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "man1", "foo-old.1"), []byte("gone"), 0644))
+
+	result, err := CheckDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.Empty(t, result.Stale)
+	assert.Empty(t, result.Missing)
+	assert.Equal(t, []string{filepath.Join(dir, "man1", "foo-old.1")}, result.Extra)
+}
+
+func TestCheckDocsManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date, ManifestFile: "MANIFEST"}
+	cmd := checkTestCmd()
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "markdown"))
+
+	// A hand written page that shares the name prefix is not extra with a
+	// manifest, only pages that were generated before are
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo_overview.md"), []byte("# Overview"), 0644))
+	cmd = checkTestCmd()
+	cmd.RemoveCommand(cmd.Commands()[1])
+	result, err := CheckDocs(cmd, &opts, dir, "markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "foo_cat.md")}, result.Extra)
+
+	// Without the manifest the name prefix is all there is to go by
+	opts.ManifestFile = ""
+	result, err = CheckDocs(cmd, &opts, dir, "markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "foo_cat.md"), filepath.Join(dir, "foo_overview.md")}, result.Extra)
+}
@@ -0,0 +1,224 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// DocGenConfig holds the content of a YAML, TOML or JSON file that configures
+// documentation generation.  Here is an example in YAML:
+//
+//	options:
+//	  left_footer: Example 1.0
+//	  author: Ray Johnson <ray.johnson@gmail.com>
+//	  bugs: File bugs at https://github.com/rayjohnson/cobraman
+//	generators:
+//	  - template: troff
+//	    directory: man
+//	  - template: markdown
+//	    directory: docs
+//	commands:
+//	  "zap hello":
+//	    environment: HELLO_NAME sets the default name.
+type DocGenConfig struct {
+	// Options fill in the CobraManOptions used by every generator
+	Options ConfigOptions `json:"options" yaml:"options" toml:"options"`
+
+	// Generators lists the templates to generate docs with
+	Generators []ConfigGenerator `json:"generators" yaml:"generators" toml:"generators"`
+
+	// Commands overrides the annotation sections of commands by command path
+	Commands map[string]ConfigSections `json:"commands" yaml:"commands" toml:"commands"`
+}
+
+// ConfigOptions are the CobraManOptions that can be set in a config file.
+// Fields that are left empty do not change the options set in Go.
+type ConfigOptions struct {
+	Section            string   `json:"section" yaml:"section" toml:"section"`
+	Date               string   `json:"date" yaml:"date" toml:"date"` // 2006-01-02 or RFC 3339
+	CenterFooter       string   `json:"center_footer" yaml:"center_footer" toml:"center_footer"`
+	LeftFooter         string   `json:"left_footer" yaml:"left_footer" toml:"left_footer"`
+	CenterHeader       string   `json:"center_header" yaml:"center_header" toml:"center_header"`
+	Files              string   `json:"files" yaml:"files" toml:"files"`
+	Bugs               string   `json:"bugs" yaml:"bugs" toml:"bugs"`
+	Environment        string   `json:"environment" yaml:"environment" toml:"environment"`
+	Author             string   `json:"author" yaml:"author" toml:"author"`
+	Gzip               bool     `json:"gzip" yaml:"gzip" toml:"gzip"`
+	SectionDirectories bool     `json:"section_dirs" yaml:"section_dirs" toml:"section_dirs"`
+	ManifestFile       string   `json:"manifest" yaml:"manifest" toml:"manifest"`
+	Prune              bool     `json:"prune" yaml:"prune" toml:"prune"`
+	Parallelism        int      `json:"parallelism" yaml:"parallelism" toml:"parallelism"`
+	IncludeHidden      bool     `json:"include_hidden" yaml:"include_hidden" toml:"include_hidden"`
+	IncludeHiddenFlags bool     `json:"include_hidden_flags" yaml:"include_hidden_flags" toml:"include_hidden_flags"`
+	IncludeDeprecated  bool     `json:"include_deprecated" yaml:"include_deprecated" toml:"include_deprecated"`
+	ExcludeHelpTopics  bool     `json:"exclude_help_topics" yaml:"exclude_help_topics" toml:"exclude_help_topics"`
+	HelpTopicSection   string   `json:"help_topic_section" yaml:"help_topic_section" toml:"help_topic_section"`
+	AliasPages         string   `json:"alias_pages" yaml:"alias_pages" toml:"alias_pages"`
+	Include            []string `json:"include" yaml:"include" toml:"include"`
+	Exclude            []string `json:"exclude" yaml:"exclude" toml:"exclude"`
+}
+
+// ConfigGenerator selects a template to generate docs with.
+type ConfigGenerator struct {
+	Template string `json:"template" yaml:"template" toml:"template"`
+
+	// Directory defaults to the --directory flag of the DocGenTool
+	Directory string `json:"directory" yaml:"directory" toml:"directory"`
+
+	// Archive if set names a .tar.gz, .tgz or .zip file to write the docs
+	// into instead of Directory
+	Archive string `json:"archive" yaml:"archive" toml:"archive"`
+}
+
+// ConfigSections override the man-*-section annotations of a command.
+type ConfigSections struct {
+	Environment string `json:"environment" yaml:"environment" toml:"environment"`
+	Files       string `json:"files" yaml:"files" toml:"files"`
+	Bugs        string `json:"bugs" yaml:"bugs" toml:"bugs"`
+	Examples    string `json:"examples" yaml:"examples" toml:"examples"`
+}
+
+// LoadConfig reads a DocGenConfig from a file.  The format is chosen by the
+// file extension: .yaml or .yml, .toml or .json.  Keys that are not known
+// are an error so that a misspelled option doesn't go unnoticed.
+func LoadConfig(filename string) (*DocGenConfig, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &DocGenConfig{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(content, config)
+	case ".toml":
+		var meta toml.MetaData
+		if meta, err = toml.Decode(string(content), config); err == nil {
+			if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+				keys := make([]string, len(undecoded))
+				for i, key := range undecoded {
+					keys[i] = key.String()
+				}
+				err = fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+			}
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	default:
+		return nil, fmt.Errorf("unknown config file format: %s", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	for _, gen := range config.Generators {
+		if !hasTemplate(gen.Template) {
+			return nil, fmt.Errorf("%s: the given template has not been registered: %s", filename, gen.Template)
+		}
+	}
+	return config, nil
+}
+
+// Apply sets the fields of opts that are set in the config options.
+func (c *ConfigOptions) Apply(opts *CobraManOptions) error {
+	if c.Date != "" {
+		date, err := parseDate(c.Date)
+		if err != nil {
+			return err
+		}
+		opts.Date = &date
+	}
+
+	setString := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	setString(&opts.Section, c.Section)
+	setString(&opts.CenterFooter, c.CenterFooter)
+	setString(&opts.LeftFooter, c.LeftFooter)
+	setString(&opts.CenterHeader, c.CenterHeader)
+	setString(&opts.Files, c.Files)
+	setString(&opts.Bugs, c.Bugs)
+	setString(&opts.Environment, c.Environment)
+	setString(&opts.Author, c.Author)
+	setString(&opts.ManifestFile, c.ManifestFile)
+	setString(&opts.HelpTopicSection, c.HelpTopicSection)
+	if c.AliasPages != "" {
+		opts.AliasPages = AliasPageMode(c.AliasPages)
+	}
+	opts.Gzip = opts.Gzip || c.Gzip
+	opts.SectionDirectories = opts.SectionDirectories || c.SectionDirectories
+	opts.Prune = opts.Prune || c.Prune
+	opts.IncludeHidden = opts.IncludeHidden || c.IncludeHidden
+	opts.IncludeHiddenFlags = opts.IncludeHiddenFlags || c.IncludeHiddenFlags
+	opts.IncludeDeprecated = opts.IncludeDeprecated || c.IncludeDeprecated
+	opts.ExcludeHelpTopics = opts.ExcludeHelpTopics || c.ExcludeHelpTopics
+	if c.Parallelism != 0 {
+		opts.Parallelism = c.Parallelism
+	}
+	if len(c.Include) > 0 {
+		opts.Include = c.Include
+	}
+	if len(c.Exclude) > 0 {
+		opts.Exclude = c.Exclude
+	}
+	return nil
+}
+
+// ApplyCommands sets the man-*-section annotations of the commands listed in
+// the config.  It returns an error if a command path can't be found under root.
+func (c *DocGenConfig) ApplyCommands(root *cobra.Command) error {
+	for path, sections := range c.Commands {
+		cmd, err := findCommand(root, strings.Fields(path))
+		if err != nil {
+			return err
+		}
+		if cmd.Annotations == nil {
+			cmd.Annotations = make(map[string]string)
+		}
+		for name, value := range map[string]string{
+			"man-environment-section": sections.Environment,
+			"man-files-section":       sections.Files,
+			"man-bugs-section":        sections.Bugs,
+			"man-examples-section":    sections.Examples,
+		} {
+			if value != "" {
+				cmd.Annotations[name] = value
+			}
+		}
+	}
+	return nil
+}
+
+// parseDate accepts a plain date or a full RFC 3339 time stamp.
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,115 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `options:
+  section: "8"
+  date: 1968-06-21
+  left_footer: Example 1.0
+  author: Ray Johnson
+generators:
+  - template: troff
+    directory: man
+  - template: markdown
+commands:
+  "foo bar":
+    environment: BAR_HOME is used
+`
+
+const tomlConfig = `[options]
+section = "8"
+date = "1968-06-21"
+left_footer = "Example 1.0"
+author = "Ray Johnson"
+
+[[generators]]
+template = "troff"
+directory = "man"
+
+[[generators]]
+template = "markdown"
+
+[commands."foo bar"]
+environment = "BAR_HOME is used"
+`
+
+const jsonConfig = `{
+  "options": {"section": "8", "date": "1968-06-21", "left_footer": "Example 1.0", "author": "Ray Johnson"},
+  "generators": [{"template": "troff", "directory": "man"}, {"template": "markdown"}],
+  "commands": {"foo bar": {"environment": "BAR_HOME is used"}}
+}`
+
+func writeConfig(t *testing.T, dir string, name string, content string) string {
+	filename := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+	return filename
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{"docs.yaml": yamlConfig, "docs.toml": tomlConfig, "docs.json": jsonConfig} {
+		config, err := LoadConfig(writeConfig(t, dir, name, content))
+		assert.NoError(t, err, name)
+		assert.Equal(t, []ConfigGenerator{{Template: "troff", Directory: "man"}, {Template: "markdown"}}, config.Generators, name)
+		assert.Equal(t, "BAR_HOME is used", config.Commands["foo bar"].Environment, name)
+
+		opts := CobraManOptions{LeftFooter: "from Go", Bugs: "from Go"}
+		assert.NoError(t, config.Options.Apply(&opts), name)
+		assert.Equal(t, "8", opts.Section, name)
+		assert.Equal(t, "Example 1.0", opts.LeftFooter, name)
+		assert.Equal(t, "from Go", opts.Bugs, name)
+		assert.Equal(t, "1968-06-21", opts.Date.Format("2006-01-02"), name)
+	}
+
+	_, err = LoadConfig(writeConfig(t, dir, "docs.txt", yamlConfig))
+	assert.Error(t, err)
+	_, err = LoadConfig(writeConfig(t, dir, "bad.yaml", "generators:\n  - template: nope\n"))
+	assert.Error(t, err)
+	_, err = LoadConfig(writeConfig(t, dir, "typo.yaml", "option:\n  section: 8\n"))
+	assert.Error(t, err)
+	_, err = LoadConfig(writeConfig(t, dir, "typo.json", `{"options": {"left_foter": "Example 1.0"}}`))
+	assert.Error(t, err)
+	_, err = LoadConfig(writeConfig(t, dir, "typo.toml", "[options]\nleft_foter = \"Example 1.0\"\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "options.left_foter")
+	}
+}
+
+func TestApplyCommands(t *testing.T) {
+	cmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.AddCommand(cmd2)
+
+	config := DocGenConfig{Commands: map[string]ConfigSections{"foo bar": {Files: "/etc/bar"}}}
+	assert.NoError(t, config.ApplyCommands(cmd))
+	assert.Equal(t, "/etc/bar", cmd2.Annotations["man-files-section"])
+	_, exists := cmd2.Annotations["man-bugs-section"]
+	assert.False(t, exists)
+
+	config = DocGenConfig{Commands: map[string]ConfigSections{"foo nope": {Files: "/etc/bar"}}}
+	assert.Error(t, config.ApplyCommands(cmd))
+}
@@ -25,6 +25,7 @@ import (
 	"os"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/rayjohnson/cobraman"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -71,6 +72,9 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	// Let users read the man pages even where they are not installed
+	cobraman.AddManCommand(rootCmd, nil)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -22,6 +22,10 @@ func main() {
 	docGenerator.AddDocGenerator(manOpts, "mdoc")
 	docGenerator.AddDocGenerator(manOpts, "troff")
 	docGenerator.AddDocGenerator(manOpts, "markdown")
+	docGenerator.AddTemplateLister()
+	docGenerator.AddConfigGenerator(nil)
+	docGenerator.AddPreviewer(manOpts)
+	docGenerator.AddDocServer(manOpts)
 
 	if err := docGenerator.Execute(); err != nil {
 		os.Exit(1)
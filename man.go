@@ -16,17 +16,16 @@
 package cobraman
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"reflect"
-	"runtime"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
 )
 
 // CobraManOptions is used configure how GenerateManPages will
@@ -73,6 +72,78 @@ type CobraManOptions struct {
 	// Author if set will create a Author section with this content.
 	Author string
 
+	// Gzip if set will compress each generated file with gzip and add a .gz
+	// suffix to its name.  The gzip header does not record a name or a
+	// modification time so the output is reproducible.
+	Gzip bool
+
+	// SectionDirectories if set will place each page in a man<Section>
+	// sub directory (e.g. man1/app.1) of the output directory.  This is the
+	// layout man expects so the output directory can be used in MANPATH.
+	SectionDirectories bool
+
+	// ManifestFile if set names a file, relative to the output directory, in
+	// which GenerateDocs records the files it wrote.
+	ManifestFile string
+
+	// Prune if set will delete the files listed in the ManifestFile of the
+	// previous run that are no longer generated, for example because a
+	// command was removed or renamed.  Files that are not in the manifest are
+	// never touched.  Prune requires ManifestFile to be set.
+	Prune bool
+
+	// Parallelism if greater than 1 lets GenerateDocs generate up to that
+	// many pages at the same time.  The files are the same as when they are
+	// generated one at a time.
+	Parallelism int
+
+	// IncludeHidden if set generates pages for hidden commands too.  The
+	// built-in templates mark them as internal.
+	IncludeHidden bool
+
+	// IncludeHiddenFlags if set lists hidden flags too.  The built-in
+	// templates mark them as internal.
+	IncludeHiddenFlags bool
+
+	// IncludeDeprecated if set generates pages for deprecated commands too
+	// and lists deprecated commands and flags in a DEPRECATED section along
+	// with their deprecation messages.
+	IncludeDeprecated bool
+
+	// ExcludeHelpTopics if set leaves out the pages of additional help topic
+	// commands, the commands without a Run that hold conceptual material
+	// such as "app help environment".  Otherwise they get a page in the
+	// HelpTopicSection that is listed in the SEE ALSO section of their parent.
+	ExcludeHelpTopics bool
+
+	// HelpTopicSection is the man section of the help topic pages (7 is the
+	// default if not set)
+	HelpTopicSection string
+
+	// AliasPages if set adds a page for every alias of a command, named as
+	// if the alias was the name of the command.  It can be SoAliasPages for
+	// a page that only includes the page of the command, which needs a man
+	// template, or SymlinkAliasPages for a symbolic link to it.
+	AliasPages AliasPageMode
+
+	// Filter if set is called for each command below the one docs are
+	// generated for.  Returning false leaves out the command and all of its
+	// children.  See HasPage for the commands that are always left out.
+	// With Parallelism set it may be called from several goroutines.
+	Filter func(cmd *cobra.Command) bool
+
+	// Include if set limits the pages generated to the commands whose
+	// command path (e.g. "app remote add") matches one of these patterns.
+	// The patterns use the syntax of path.Match and as * also matches
+	// spaces "app remote *" matches every command below "app remote".
+	// Children of a command that doesn't match are still looked at.
+	Include []string
+
+	// Exclude leaves out the commands whose command path matches one of
+	// these patterns, and all of their children.  The patterns are the same
+	// as for Include.
+	Exclude []string
+
 	// Private fields
 
 	// fileCmdSeparator defines what character to use to separate the
@@ -82,6 +153,9 @@ type CobraManOptions struct {
 	// fileSuffix is the file extension to use for file name.  Defaults to the section
 	// for man templates and .md for the MarkdownTemplate template.
 	fileSuffix string
+
+	// useSection is set if the file extension is the section of the page.
+	useSection bool
 }
 
 // GenerateDocs - build man pages for the passed in cobra.Command
@@ -93,260 +167,221 @@ func GenerateDocs(cmd *cobra.Command, opts *CobraManOptions, directory string, t
 		directory = "."
 	}
 
-	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
-		}
-		if err := GenerateDocs(c, opts, directory, templateName); err != nil {
+	if opts.Prune && opts.ManifestFile == "" {
+		return fmt.Errorf("a ManifestFile is needed to prune generated files")
+	}
+
+	files, err := pageFiles(cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	// Read the old manifest before anything is written
+	var previous []string
+	manifest := filepath.Join(directory, opts.ManifestFile)
+	if opts.Prune {
+		if previous, err = readManifest(manifest); err != nil {
 			return err
 		}
 	}
 
-	// Generate file name and open the file
-	basename := strings.Replace(cmd.CommandPath(), " ", opts.fileCmdSeparator, -1)
-	if basename == "" {
-		return fmt.Errorf("you need a command name to have a man page")
-	}
-	filename := filepath.Join(directory, basename+"."+opts.fileSuffix)
-	f, err := os.Create(filename)
+	err = forEachPage(files, opts, func(n int, opts *CobraManOptions) error {
+		return writeDocFile(filepath.Join(directory, files[n].name), files[n], opts, templateName)
+	})
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	// Generate the documentation
-	return GenerateOnePage(cmd, opts, templateName, f)
+	if opts.Prune {
+		if err := pruneFiles(directory, previous, files); err != nil {
+			return err
+		}
+	}
+	if opts.ManifestFile != "" {
+		return writeManifest(manifest, files)
+	}
+	return nil
 }
 
-func validate(opts *CobraManOptions, templateName string) {
-	if opts.Section == "" {
-		opts.Section = "1"
-	}
-	if opts.Date == nil {
-		now := time.Now()
-		opts.Date = &now
+// DocFile describes a file that GenerateDocs will write.
+type DocFile struct {
+	// Path of the file including the directory passed to ListDocs
+	Path string
+
+	// CommandPath of the command documented in the file (e.g. "git commit")
+	CommandPath string
+}
+
+// ListDocs returns the files GenerateDocs would write for the passed in
+// cobra.Command and all of its children without touching the filesystem.
+func ListDocs(cmd *cobra.Command, opts *CobraManOptions, directory string, templateName string) ([]DocFile, error) {
+	// Set defaults
+	validate(opts, templateName)
+	if directory == "" {
+		directory = "."
 	}
 
-	sep, ext, t := getTemplate(templateName)
-	if t == nil {
-		panic("template could not be found: " + templateName)
+	files, err := pageFiles(cmd, opts)
+	if err != nil {
+		return nil, err
 	}
-	opts.fileCmdSeparator = sep
-	opts.fileSuffix = ext
-	if ext == "use_section" {
-		opts.fileSuffix = opts.Section
+	docs := make([]DocFile, 0, len(files))
+	for _, pf := range files {
+		docs = append(docs, DocFile{
+			Path:        filepath.Join(directory, pf.name),
+			CommandPath: pf.cmd.CommandPath(),
+		})
 	}
+	return docs, nil
 }
 
-type manStruct struct {
-	Date             *time.Time
-	Section          string
-	CenterFooter     string
-	LeftFooter       string
-	CenterHeader     string
-	UseLine          string
-	CommandPath      string
-	ShortDescription string
-	Description      string
-	NoArgs           bool
-
-	AllFlags          []manFlag
-	InheritedFlags    []manFlag
-	NonInheritedFlags []manFlag
-	SeeAlsos          []seeAlso
-	SubCommands       []string
-
-	Author      string
-	Environment string
-	Files       string
-	Bugs        string
-	Examples    string
+// pageFile is a file that GenerateDocs will write for a command.
+type pageFile struct {
+	name string // relative to the output directory
+	cmd  *cobra.Command
 
-	CobraCmd *cobra.Command
+	// target is set for the page of an alias to the name of the page of
+	// the command it is an alias of
+	target string
 }
 
-type manFlag struct {
-	Shorthand   string
-	Name        string
-	NoOptDefVal string
-	DefValue    string
-	Usage       string
-	ArgHint     string
+// pageFiles walks cmd and its children and returns the files to generate
+// for them.  The opts must already have been validated.
+func pageFiles(cmd *cobra.Command, opts *CobraManOptions) ([]pageFile, error) {
+	files := make([]pageFile, 0)
+	err := WalkCommands(cmd, opts, func(c *cobra.Command) error {
+		basename := strings.Replace(c.CommandPath(), " ", opts.fileCmdSeparator, -1)
+		if basename == "" {
+			return fmt.Errorf("you need a command name to have a man page")
+		}
+		section := opts.pageSection(c)
+		name := basename + opts.fileExtension(section)
+		if opts.SectionDirectories {
+			name = filepath.Join("man"+section, name)
+		}
+		files = append(files, pageFile{name: name, cmd: c})
+		aliases, err := aliasPageFiles(c, name, opts)
+		files = append(files, aliases...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
 }
 
-type seeAlso struct {
-	CmdPath   string
-	Section   string
-	IsParent  bool
-	IsChild   bool
-	IsSibling bool
+func writeDocFile(filename string, pf pageFile, opts *CobraManOptions, templateName string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	if pf.target != "" && opts.AliasPages == SymlinkAliasPages {
+		return writeAliasLink(filename, pf)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return generateDocFile(f, pf, opts, templateName)
 }
 
-// GenerateOnePage will generate one documentation page and output the result to w
-// TODO: document use of this function in README
-func GenerateOnePage(cmd *cobra.Command, opts *CobraManOptions, templateName string, w io.Writer) error {
-	// Set defaults - these would already be set unless GenerateOnePage called directly
-	validate(opts, templateName)
+// generateDocFile writes the content of the file for pf to w.
+func generateDocFile(w io.Writer, pf pageFile, opts *CobraManOptions, templateName string) error {
+	if !opts.Gzip {
+		return generatePage(w, pf, opts, templateName)
+	}
+	// The header's Name and ModTime are left unset to keep the output reproducible
+	zw := gzip.NewWriter(w)
+	if err := generatePage(zw, pf, opts, templateName); err != nil {
+		return err
+	}
+	return zw.Close()
+}
 
-	values := manStruct{}
-
-	// Header fields
-	values.LeftFooter = opts.LeftFooter
-	values.CenterHeader = opts.CenterHeader
-	values.Section = opts.Section
-	values.Date = opts.Date
-	values.CenterFooter = opts.CenterFooter
-	if opts.CenterFooter == "" {
-		// TODO: should this be part of template instead?
-		values.CenterFooter = values.Date.Format("Jan 2006")
+// generatePage writes the uncompressed content of the file for pf to w.
+func generatePage(w io.Writer, pf pageFile, opts *CobraManOptions, templateName string) error {
+	if pf.target != "" {
+		_, err := io.WriteString(w, opts.soRequest(pf))
+		return err
 	}
+	return GenerateOnePage(pf.cmd, opts, templateName, w)
+}
 
-	values.CobraCmd = cmd
-	values.ShortDescription = cmd.Short
-	values.UseLine = cmd.UseLine()
-	values.CommandPath = cmd.CommandPath()
-
-	// Use reflection to see if cobra.NoArgs was set
-	argFuncName := runtime.FuncForPC(reflect.ValueOf(cmd.Args).Pointer()).Name()
-	values.NoArgs = strings.HasSuffix(argFuncName, "cobra.NoArgs")
-
-	if cmd.HasSubCommands() {
-		subCmdArr := make([]string, 0, 10)
-		for _, c := range cmd.Commands() {
-			if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-				continue
-			}
-			subCmdArr = append(subCmdArr, c.CommandPath())
-		}
-		values.SubCommands = subCmdArr
+// readDocFile returns the content of a file written by writeDocFile.
+func readDocFile(filename string, opts *CobraManOptions) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
 
-	// DESCRIPTION
-	description := cmd.Long
-	if len(description) == 0 {
-		description = cmd.Short
+	if !opts.Gzip {
+		return ioutil.ReadAll(f)
 	}
-	values.Description = description
-
-	// Flag arrays
-	values.AllFlags = genFlagArray(cmd.Flags())
-	values.InheritedFlags = genFlagArray(cmd.InheritedFlags())
-	values.NonInheritedFlags = genFlagArray(cmd.NonInheritedFlags())
-
-	// ENVIRONMENT section
-	altEnvironmentSection, _ := cmd.Annotations["man-environment-section"]
-	if opts.Environment != "" || altEnvironmentSection != "" {
-		if altEnvironmentSection != "" {
-			values.Environment = altEnvironmentSection
-		} else {
-			values.Environment = opts.Environment
-		}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
 	}
+	return ioutil.ReadAll(zr)
+}
 
-	// FILES section
-	altFilesSection, _ := cmd.Annotations["man-files-section"]
-	if opts.Files != "" || altFilesSection != "" {
-		if altFilesSection != "" {
-			values.Files = altFilesSection
-		} else {
-			values.Files = opts.Files
-		}
+func validate(opts *CobraManOptions, templateName string) {
+	setDefaults(opts)
+
+	sep, ext, t := getTemplate(templateName)
+	if t == nil {
+		panic("template could not be found: " + templateName)
+	}
+	opts.fileCmdSeparator = sep
+	opts.fileSuffix = ext
+	opts.useSection = ext == "use_section"
+	if opts.useSection {
+		opts.fileSuffix = opts.Section
 	}
+}
 
-	// BUGS section
-	altBugsSection, _ := cmd.Annotations["man-bugs-section"]
-	if opts.Bugs != "" || altBugsSection != "" {
-		if altBugsSection != "" {
-			values.Bugs = altBugsSection
-		} else {
-			values.Bugs = opts.Bugs
-		}
+// setDefaults fills in the fields of opts that have a default.
+func setDefaults(opts *CobraManOptions) {
+	if opts.Section == "" {
+		opts.Section = "1"
+	}
+	if opts.HelpTopicSection == "" {
+		opts.HelpTopicSection = "7"
 	}
+	if opts.Date == nil {
+		now := time.Now()
+		opts.Date = &now
+	}
+}
 
-	// EXAMPLES section
-	altExampleSection, _ := cmd.Annotations["man-examples-section"]
-	if cmd.Example != "" || altExampleSection != "" {
-		if altExampleSection != "" {
-			values.Examples = altExampleSection
-		} else {
-			values.Examples = cmd.Example
-		}
+// fileExtension returns the extension, including the leading dot, of the
+// generated files for pages in section.  The opts must already have been
+// validated.
+func (opts *CobraManOptions) fileExtension(section string) string {
+	ext := "." + opts.fileSuffix
+	if opts.useSection {
+		ext = "." + section
+	}
+	if opts.Gzip {
+		ext += ".gz"
 	}
+	return ext
+}
 
-	// AUTHOR section
-	values.Author = opts.Author
+// GenerateOnePage will generate one documentation page and output the result to w.
+// The template is executed with the ManPage that BuildPage returns for cmd.
+func GenerateOnePage(cmd *cobra.Command, opts *CobraManOptions, templateName string, w io.Writer) error {
+	// Set defaults - these would already be set unless GenerateOnePage called directly
+	validate(opts, templateName)
 
-	// SEE ALSO section
-	values.SeeAlsos = generateSeeAlsos(cmd, values.Section)
+	page := BuildPage(cmd, opts)
 
 	// Get template and generate the documentation page
 	_, _, t := getTemplate(templateName)
-	err := t.Execute(w, values)
+	err := t.Execute(w, page)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-
-func genFlagArray(flags *pflag.FlagSet) []manFlag {
-	flagArray := make([]manFlag, 0, 15)
-	flags.VisitAll(func(flag *pflag.Flag) {
-		if len(flag.Deprecated) > 0 || flag.Hidden {
-			return
-		}
-		thisFlag := manFlag{
-			Name:        flag.Name,
-			NoOptDefVal: flag.NoOptDefVal,
-			DefValue:    flag.DefValue,
-			Usage:       flag.Usage,
-		}
-		if len(flag.ShorthandDeprecated) == 0 {
-			thisFlag.Shorthand = flag.Shorthand
-		}
-		hintArr, exists := flag.Annotations["man-arg-hints"]
-		if exists && len(hintArr) > 0 {
-			thisFlag.ArgHint = hintArr[0]
-		}
-		flagArray = append(flagArray, thisFlag)
-	})
-
-	return flagArray
-}
-
-func generateSeeAlsos(cmd *cobra.Command, section string) []seeAlso {
-	seealsos := make([]seeAlso, 0)
-	if cmd.HasParent() {
-		see := seeAlso{
-			CmdPath:  cmd.Parent().CommandPath(),
-			Section:  section,
-			IsParent: true,
-		}
-		seealsos = append(seealsos, see)
-		siblings := cmd.Parent().Commands()
-		for _, c := range siblings {
-			if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() || c.Name() == cmd.Name() {
-				continue
-			}
-			see := seeAlso{
-				CmdPath:   c.CommandPath(),
-				Section:   section,
-				IsSibling: true,
-			}
-			seealsos = append(seealsos, see)
-		}
-	}
-	children := cmd.Commands()
-	for _, c := range children {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
-		}
-		see := seeAlso{
-			CmdPath: c.CommandPath(),
-			Section: section,
-			IsChild: true,
-		}
-		seealsos = append(seealsos, see)
-	}
-
-	return seealsos
-}
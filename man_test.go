@@ -15,7 +15,10 @@ package cobraman
 
 import (
 	"bytes"
+	"compress/gzip"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -71,6 +74,85 @@ func TestGenerateManPages(t *testing.T) {
 
 }
 
+func TestGzipDocs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cmd := &cobra.Command{Use: "foo", Short: "compress me"}
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date, Gzip: true}
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	checkFileNotExist(t, filepath.Join(dir, "foo.1"))
+
+	f, err := os.Open(filepath.Join(dir, "foo.1.gz"))
+	assert.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	assert.True(t, zr.ModTime.IsZero())
+	assert.Empty(t, zr.Name)
+	content, err := ioutil.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Regexp(t, ".SH NAME\nfoo - compress me", string(content))
+
+	// Generating again gives byte for byte the same file
+	first, _ := ioutil.ReadFile(filepath.Join(dir, "foo.1.gz"))
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	second, _ := ioutil.ReadFile(filepath.Join(dir, "foo.1.gz"))
+	assert.Equal(t, first, second)
+
+	result, err := CheckDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.True(t, result.IsCurrent())
+}
+
+func TestSectionDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.AddCommand(cmd2)
+
+	opts := CobraManOptions{SectionDirectories: true}
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	checkForFile(t, filepath.Join(dir, "man1", "foo.1"))
+	checkForFile(t, filepath.Join(dir, "man1", "foo-bar.1"))
+
+	opts = CobraManOptions{Section: "5", SectionDirectories: true, Gzip: true}
+	docs, err := ListDocs(cmd, &opts, dir, "troff")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "man5", "foo-bar.5.gz"), docs[1].Path)
+}
+
+func TestListDocs(t *testing.T) {
+	cmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd3 := &cobra.Command{Use: "cat", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd2.AddCommand(cmd3)
+	cmd.AddCommand(cmd2)
+
+	opts := CobraManOptions{Section: "8"}
+	docs, err := ListDocs(cmd, &opts, "out", "troff")
+	assert.NoError(t, err)
+	assert.Equal(t, []DocFile{
+		{Path: filepath.Join("out", "foo.8"), CommandPath: "foo"},
+		{Path: filepath.Join("out", "foo-bar.8"), CommandPath: "foo bar"},
+		{Path: filepath.Join("out", "foo-bar-cat.8"), CommandPath: "foo bar cat"},
+	}, docs)
+	checkFileNotExist(t, "out")
+
+	opts = CobraManOptions{}
+	docs, err = ListDocs(cmd, &opts, "", "markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo_bar_cat.md", docs[2].Path)
+
+	_, err = ListDocs(&cobra.Command{}, &opts, "", "troff")
+	assert.Error(t, err)
+}
+
 func TestSetCobraManOptDefaults(t *testing.T) {
 	opts := CobraManOptions{}
 
@@ -0,0 +1,91 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const manifestHeader = "# Files generated by github.com/rayjohnson/cobraman"
+
+// readManifest returns the file names listed in a manifest.  A manifest that
+// does not exist yet is the same as an empty one.
+func readManifest(filename string) ([]string, error) {
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		names = append(names, filepath.FromSlash(line))
+	}
+	return names, scanner.Err()
+}
+
+// writeManifest records the names of the generated files, relative to the
+// output directory, in the manifest.
+func writeManifest(filename string, files []pageFile) error {
+	return ioutil.WriteFile(filename, manifestContent(files), 0644)
+}
+
+func manifestContent(files []pageFile) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintln(buf, manifestHeader)
+	for _, pf := range files {
+		fmt.Fprintln(buf, filepath.ToSlash(pf.name))
+	}
+	return buf.Bytes()
+}
+
+// pruneFiles deletes the files listed in the previous manifest that are not
+// in files.  Names that would reach outside of directory are ignored so a
+// tampered manifest can't be used to delete anything else.
+func pruneFiles(directory string, previous []string, files []pageFile) error {
+	generated := make(map[string]bool)
+	for _, pf := range files {
+		generated[pf.name] = true
+	}
+
+	for _, name := range previous {
+		name = filepath.Clean(name)
+		if generated[name] || outsideDirectory(name) {
+			continue
+		}
+		err := os.Remove(filepath.Join(directory, name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// outsideDirectory reports whether the cleaned name from a manifest would
+// reach outside of the output directory.
+func outsideDirectory(name string) bool {
+	return filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,74 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestAndPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd3 := &cobra.Command{Use: "cat", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.AddCommand(cmd2, cmd3)
+
+	opts := CobraManOptions{ManifestFile: ".manifest", SectionDirectories: true}
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	content, err := ioutil.ReadFile(filepath.Join(dir, ".manifest"))
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+"\nman1/foo.1\nman1/foo-bar.1\nman1/foo-cat.1\n", string(content))
+
+	// A file we did not generate must survive pruning
+	notOurs := filepath.Join(dir, "man1", "foo-notours.1")
+	assert.NoError(t, ioutil.WriteFile(notOurs, []byte("keep me"), 0644))
+
+	cmd.RemoveCommand(cmd3)
+	opts.Prune = true
+	assert.NoError(t, GenerateDocs(cmd, &opts, dir, "troff"))
+	checkFileNotExist(t, filepath.Join(dir, "man1", "foo-cat.1"))
+	checkForFile(t, filepath.Join(dir, "man1", "foo.1"))
+	checkForFile(t, filepath.Join(dir, "man1", "foo-bar.1"))
+	checkForFile(t, notOurs)
+	names, err := readManifest(filepath.Join(dir, ".manifest"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("man1", "foo.1"), filepath.Join("man1", "foo-bar.1")}, names)
+
+	opts = CobraManOptions{Prune: true}
+	assert.Error(t, GenerateDocs(cmd, &opts, dir, "troff"))
+}
+
+func TestPruneStaysInDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outside := filepath.Join(dir, "outside.1")
+	assert.NoError(t, ioutil.WriteFile(outside, []byte("keep me"), 0644))
+	docs := filepath.Join(dir, "docs")
+	assert.NoError(t, os.Mkdir(docs, 0755))
+
+	previous := []string{filepath.Join("..", "outside.1"), outside}
+	assert.NoError(t, pruneFiles(docs, previous, nil))
+	checkForFile(t, outside)
+}
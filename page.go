@@ -0,0 +1,440 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ManPage is the model of a documentation page.  It holds everything a
+// template can use and is what templates are executed with, so its fields
+// are the variables described in WRITING_A_TEMPLATE.md.
+type ManPage struct {
+	// Date of the page (CobraManOptions.Date or now)
+	Date *time.Time
+
+	// Section of the man page (CobraManOptions.Section or "1", or
+	// CobraManOptions.HelpTopicSection for a help topic)
+	Section string
+
+	// CenterFooter defaults to the month and year of Date
+	CenterFooter string
+	LeftFooter   string
+	CenterHeader string
+
+	// UseLine is the cobra use line of the command
+	UseLine string
+
+	// CommandPath is the space separated path of the command (e.g. "git commit")
+	CommandPath string
+
+	// Aliases are the aliases of the command (e.g. "ci" for "git commit")
+	// and AliasPaths the command paths they give (e.g. "git ci")
+	Aliases    []string
+	AliasPaths []string
+
+	ShortDescription string
+
+	// Description is the Long description of the command or the Short one
+	// if it has none
+	Description string
+
+	// NoArgs is true if the command takes no arguments (e.g. it uses
+	// cobra.NoArgs)
+	NoArgs bool
+
+	// Args is the model of the positional arguments of the command
+	Args ManArgs
+
+	// Hidden is true for a hidden command, which only gets a page with
+	// CobraManOptions.IncludeHidden set
+	Hidden bool
+
+	// Deprecated is the deprecation message of the command
+	Deprecated string
+
+	// IsHelpTopic is true for an additional help topic command.  Help
+	// topics can't be run so they have no flags.
+	IsHelpTopic bool
+
+	// AllFlags holds the flags set on cmd.Flags() that are not persistent,
+	// InheritedFlags those the command gets from its parents and
+	// NonInheritedFlags its own including the persistent ones
+	AllFlags          []ManFlag
+	InheritedFlags    []ManFlag
+	NonInheritedFlags []ManFlag
+
+	// SeeAlsos lists the parent, siblings and children of the command
+	SeeAlsos []SeeAlso
+
+	// DeprecatedFlags holds the flags of the command that are deprecated or
+	// have a deprecated shorthand and DeprecatedCommands its deprecated
+	// children.  Both are only set with CobraManOptions.IncludeDeprecated.
+	DeprecatedFlags    []ManFlag
+	DeprecatedCommands []SeeAlso
+
+	// SubCommands holds the children of the command that can be run
+	SubCommands []SeeAlso
+
+	// The content of the AUTHOR, ENVIRONMENT, FILES, BUGS and EXAMPLES sections
+	Author      string
+	Environment string
+	Files       string
+	Bugs        string
+	Examples    string
+
+	// CobraCmd is the command the page is for
+	CobraCmd *cobra.Command
+}
+
+// ManFlag is the model of a flag in a ManPage.
+type ManFlag struct {
+	// Shorthand is the one letter name of the flag (e.g. "h")
+	Shorthand string
+
+	// Name is the long name of the flag (e.g. "help")
+	Name string
+
+	// NoOptDefVal is the value the flag gets when it is given without one
+	NoOptDefVal string
+
+	DefValue string
+
+	// Usage is the usage of the flag without the back quotes that may
+	// name its argument
+	Usage string
+
+	// Type is the type of the flag's value (e.g. "duration")
+	Type string
+
+	// Default is the DefValue as pflag shows it in its help, which quotes
+	// strings.  It is empty if the flag defaults to the zero value of its
+	// Type.
+	Default string
+
+	// ArgHint is the value of the flag's "man-arg-hints" annotation
+	ArgHint string
+
+	// Placeholder names the argument of the flag.  It is the ArgHint if
+	// set, else the back-quoted word in the usage or a name for the Type
+	// as pflag uses in its help (e.g. "strings" for a string slice).  It
+	// is empty for a bool flag.
+	Placeholder string
+
+	// Hidden is true for a hidden flag, which is only listed with
+	// CobraManOptions.IncludeHiddenFlags set
+	Hidden bool
+
+	// Deprecated is the deprecation message of the flag and
+	// ShorthandDeprecated that of its shorthand
+	Deprecated          string
+	ShorthandDeprecated string
+}
+
+// SeeAlso is the model of a related command in a ManPage.
+type SeeAlso struct {
+	// CmdPath is the space separated path of the command
+	CmdPath string
+
+	// Section of the man page of the command
+	Section string
+
+	IsParent  bool
+	IsChild   bool
+	IsSibling bool
+
+	// Hidden is true if the command is hidden
+	Hidden bool
+
+	// Deprecated is the deprecation message of the command
+	Deprecated string
+
+	// IsHelpTopic is true if the command is an additional help topic
+	IsHelpTopic bool
+}
+
+// BuildPage returns the model of the page for cmd.  Fields that are not
+// set in opts get their defaults; opts itself is not changed.
+func BuildPage(cmd *cobra.Command, opts *CobraManOptions) *ManPage {
+	defaults := *opts
+	opts = &defaults
+	setDefaults(opts)
+
+	values := &ManPage{}
+
+	// Header fields
+	values.LeftFooter = opts.LeftFooter
+	values.CenterHeader = opts.CenterHeader
+	values.Section = opts.pageSection(cmd)
+	values.Date = opts.Date
+	values.CenterFooter = opts.CenterFooter
+	if opts.CenterFooter == "" {
+		// TODO: should this be part of template instead?
+		values.CenterFooter = values.Date.Format("Jan 2006")
+	}
+
+	values.CobraCmd = cmd
+	values.ShortDescription = cmd.Short
+	values.Hidden = cmd.Hidden
+	values.Deprecated = cmd.Deprecated
+	values.IsHelpTopic = isHelpTopic(cmd)
+	values.UseLine = cmd.UseLine()
+	values.CommandPath = cmd.CommandPath()
+	values.Aliases = cmd.Aliases
+	for _, alias := range cmd.Aliases {
+		if cmd.HasParent() {
+			alias = cmd.Parent().CommandPath() + " " + alias
+		}
+		values.AliasPaths = append(values.AliasPaths, alias)
+	}
+
+	values.Args = buildArgs(cmd)
+	values.NoArgs = values.Args.Max == 0
+
+	// DESCRIPTION
+	description := cmd.Long
+	if len(description) == 0 {
+		description = cmd.Short
+	}
+	values.Description = description
+
+	// Flag arrays - cobra merges the persistent flags into cmd.Flags() the
+	// first time the inherited flags are asked for, so AllFlags leaves them
+	// out to be the same whether that has happened or not
+	if !values.IsHelpTopic {
+		localFlags := cmd.LocalNonPersistentFlags()
+		values.AllFlags = genFlagArray(localFlags, opts)
+		values.InheritedFlags = genFlagArray(cmd.InheritedFlags(), opts)
+		values.NonInheritedFlags = genFlagArray(cmd.NonInheritedFlags(), opts)
+		if opts.IncludeDeprecated {
+			values.DeprecatedFlags = genDeprecatedFlagArray(localFlags, opts)
+		}
+	}
+
+	// ENVIRONMENT section
+	altEnvironmentSection, _ := cmd.Annotations["man-environment-section"]
+	if opts.Environment != "" || altEnvironmentSection != "" {
+		if altEnvironmentSection != "" {
+			values.Environment = altEnvironmentSection
+		} else {
+			values.Environment = opts.Environment
+		}
+	}
+
+	// FILES section
+	altFilesSection, _ := cmd.Annotations["man-files-section"]
+	if opts.Files != "" || altFilesSection != "" {
+		if altFilesSection != "" {
+			values.Files = altFilesSection
+		} else {
+			values.Files = opts.Files
+		}
+	}
+
+	// BUGS section
+	altBugsSection, _ := cmd.Annotations["man-bugs-section"]
+	if opts.Bugs != "" || altBugsSection != "" {
+		if altBugsSection != "" {
+			values.Bugs = altBugsSection
+		} else {
+			values.Bugs = opts.Bugs
+		}
+	}
+
+	// EXAMPLES section
+	altExampleSection, _ := cmd.Annotations["man-examples-section"]
+	if cmd.Example != "" || altExampleSection != "" {
+		if altExampleSection != "" {
+			values.Examples = altExampleSection
+		} else {
+			values.Examples = cmd.Example
+		}
+	}
+
+	// AUTHOR section
+	values.Author = opts.Author
+
+	// SEE ALSO section
+	values.SeeAlsos = generateSeeAlsos(cmd, opts)
+
+	// SYNOPSIS of the sub commands and DEPRECATED section
+	for _, see := range values.SeeAlsos {
+		if !see.IsChild {
+			continue
+		}
+		if !see.IsHelpTopic {
+			values.SubCommands = append(values.SubCommands, see)
+		}
+		if see.Deprecated != "" {
+			values.DeprecatedCommands = append(values.DeprecatedCommands, see)
+		}
+	}
+
+	return values
+}
+
+func genFlagArray(flags *pflag.FlagSet, opts *CobraManOptions) []ManFlag {
+	flagArray := make([]ManFlag, 0, 15)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) > 0 || (flag.Hidden && !opts.IncludeHiddenFlags) {
+			return
+		}
+		placeholder, usage := flagPlaceholder(flag)
+		thisFlag := ManFlag{
+			Name:        flag.Name,
+			NoOptDefVal: flag.NoOptDefVal,
+			DefValue:    flag.DefValue,
+			Usage:       usage,
+			Type:        flag.Value.Type(),
+			Default:     flagDefault(flag),
+			Placeholder: placeholder,
+			Hidden:      flag.Hidden,
+		}
+		if len(flag.ShorthandDeprecated) == 0 {
+			thisFlag.Shorthand = flag.Shorthand
+		}
+		hintArr, exists := flag.Annotations["man-arg-hints"]
+		if exists && len(hintArr) > 0 {
+			thisFlag.ArgHint = hintArr[0]
+		}
+		flagArray = append(flagArray, thisFlag)
+	})
+
+	return flagArray
+}
+
+// flagPlaceholder returns the placeholder of the argument of flag and its
+// usage without the back quotes that may name it.  The "man-arg-hints"
+// annotation overrides the placeholder pflag would use.
+func flagPlaceholder(flag *pflag.Flag) (string, string) {
+	placeholder, usage := pflag.UnquoteUsage(flag)
+	if hints := flag.Annotations["man-arg-hints"]; len(hints) > 0 {
+		placeholder = hints[0]
+	}
+	return placeholder, usage
+}
+
+// flagDefault returns the default of flag the way pflag shows it in its
+// help, or "" if it is the zero value of the flag's type.
+func flagDefault(flag *pflag.Flag) string {
+	typ := flag.Value.Type()
+	zero := false
+	switch {
+	case typ == "bool":
+		zero = flag.DefValue == "false"
+	case typ == "duration":
+		zero = flag.DefValue == "0" || flag.DefValue == "0s"
+	case typ == "count" || strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint") || strings.HasPrefix(typ, "float"):
+		zero = flag.DefValue == "0"
+	case typ == "string":
+		zero = flag.DefValue == ""
+	case typ == "ip" || typ == "ipMask" || typ == "ipNet":
+		zero = flag.DefValue == "<nil>"
+	case strings.HasSuffix(typ, "Slice") || typ == "stringArray":
+		zero = flag.DefValue == "[]"
+	default:
+		zero = flag.DefValue == "" || flag.DefValue == "false" || flag.DefValue == "0" || flag.DefValue == "<nil>"
+	}
+	if zero {
+		return ""
+	}
+	if typ == "string" {
+		return strconv.Quote(flag.DefValue)
+	}
+	return flag.DefValue
+}
+
+// genDeprecatedFlagArray returns the flags that are deprecated or whose
+// shorthand is.
+func genDeprecatedFlagArray(flags *pflag.FlagSet, opts *CobraManOptions) []ManFlag {
+	flagArray := make([]ManFlag, 0)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) == 0 && (len(flag.ShorthandDeprecated) == 0 || flag.Shorthand == "") {
+			return
+		}
+		// pflag hides deprecated flags so only other hidden flags are left out
+		if flag.Hidden && len(flag.Deprecated) == 0 && !opts.IncludeHiddenFlags {
+			return
+		}
+		placeholder, usage := flagPlaceholder(flag)
+		flagArray = append(flagArray, ManFlag{
+			Shorthand:           flag.Shorthand,
+			Name:                flag.Name,
+			NoOptDefVal:         flag.NoOptDefVal,
+			DefValue:            flag.DefValue,
+			Usage:               usage,
+			Type:                flag.Value.Type(),
+			Default:             flagDefault(flag),
+			Placeholder:         placeholder,
+			Hidden:              flag.Hidden && len(flag.Deprecated) == 0,
+			Deprecated:          flag.Deprecated,
+			ShorthandDeprecated: flag.ShorthandDeprecated,
+		})
+	})
+
+	return flagArray
+}
+
+func generateSeeAlsos(cmd *cobra.Command, opts *CobraManOptions) []SeeAlso {
+	seealsos := make([]SeeAlso, 0)
+	if cmd.HasParent() {
+		if opts.HasPage(cmd.Parent()) {
+			see := SeeAlso{
+				CmdPath:    cmd.Parent().CommandPath(),
+				Section:    opts.pageSection(cmd.Parent()),
+				IsParent:   true,
+				Hidden:     cmd.Parent().Hidden,
+				Deprecated: cmd.Parent().Deprecated,
+			}
+			seealsos = append(seealsos, see)
+		}
+		siblings := cmd.Parent().Commands()
+		for _, c := range siblings {
+			if c.Name() == cmd.Name() || !opts.HasPage(c) {
+				continue
+			}
+			see := SeeAlso{
+				CmdPath:     c.CommandPath(),
+				Section:     opts.pageSection(c),
+				IsSibling:   true,
+				Hidden:      c.Hidden,
+				Deprecated:  c.Deprecated,
+				IsHelpTopic: isHelpTopic(c),
+			}
+			seealsos = append(seealsos, see)
+		}
+	}
+	children := cmd.Commands()
+	for _, c := range children {
+		if !opts.HasPage(c) {
+			continue
+		}
+		see := SeeAlso{
+			CmdPath:     c.CommandPath(),
+			Section:     opts.pageSection(c),
+			IsChild:     true,
+			Hidden:      c.Hidden,
+			Deprecated:  c.Deprecated,
+			IsHelpTopic: isHelpTopic(c),
+		}
+		seealsos = append(seealsos, see)
+	}
+
+	return seealsos
+}
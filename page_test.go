@@ -0,0 +1,308 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPage(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	appCmd.PersistentFlags().BoolP("verbose", "v", false, "be loud")
+	barCmd := &cobra.Command{Use: "bar", Short: "bar things", Long: "Bar all the things", Args: cobra.NoArgs, Run: func(cmd *cobra.Command, args []string) {}}
+	barCmd.Flags().String("name", "joe", "name to use")
+	barCmd.Flags().SetAnnotation("name", "man-arg-hints", []string{"who"})
+	zapCmd := &cobra.Command{Use: "zap", Short: "zap things", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(barCmd, zapCmd)
+
+	opts := &CobraManOptions{Author: "Me", LeftFooter: "Foo 1.0"}
+	page := BuildPage(barCmd, opts)
+	assert.Nil(t, opts.Date) // opts is left alone
+	assert.Equal(t, "", opts.Section)
+
+	assert.Equal(t, "1", page.Section)
+	assert.Equal(t, time.Now().Format("Jan 2006"), page.CenterFooter)
+	assert.Equal(t, "Foo 1.0", page.LeftFooter)
+	assert.Equal(t, "foo bar", page.CommandPath)
+	assert.Equal(t, "bar things", page.ShortDescription)
+	assert.Equal(t, "Bar all the things", page.Description)
+	assert.True(t, page.NoArgs)
+	assert.Equal(t, "Me", page.Author)
+	assert.Equal(t, barCmd, page.CobraCmd)
+
+	assert.Equal(t, []ManFlag{{Name: "name", DefValue: "joe", Usage: "name to use", Type: "string", Default: `"joe"`, ArgHint: "who", Placeholder: "who"}}, page.NonInheritedFlags)
+	assert.Equal(t, []ManFlag{{Shorthand: "v", Name: "verbose", NoOptDefVal: "true", DefValue: "false", Usage: "be loud", Type: "bool"}}, page.InheritedFlags)
+	// The persistent flags of the parent are not listed as options
+	assert.Equal(t, page.NonInheritedFlags, page.AllFlags)
+
+	assert.Equal(t, []SeeAlso{
+		{CmdPath: "foo", Section: "1", IsParent: true},
+		{CmdPath: "foo zap", Section: "1", IsSibling: true},
+	}, page.SeeAlsos)
+
+	page = BuildPage(appCmd, &CobraManOptions{Section: "8"})
+	assert.Equal(t, []SeeAlso{
+		{CmdPath: "foo bar", Section: "8", IsChild: true},
+		{CmdPath: "foo zap", Section: "8", IsChild: true},
+	}, page.SubCommands)
+	assert.Equal(t, "8", page.SeeAlsos[0].Section)
+	assert.True(t, page.SeeAlsos[0].IsChild)
+}
+
+func TestIncludeHidden(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	debugCmd := &cobra.Command{Use: "debug", Short: "debug things", Hidden: true, Run: func(cmd *cobra.Command, args []string) {}}
+	debugCmd.Flags().Bool("trace", false, "trace everything")
+	debugCmd.Flags().MarkHidden("trace")
+	debugCmd.Flags().Bool("quiet", false, "say less")
+	appCmd.AddCommand(debugCmd)
+
+	opts := &CobraManOptions{}
+	assert.False(t, opts.HasPage(debugCmd))
+	assert.Empty(t, BuildPage(appCmd, opts).SeeAlsos)
+	assert.Len(t, BuildPage(debugCmd, opts).AllFlags, 1)
+
+	opts = &CobraManOptions{IncludeHidden: true, IncludeHiddenFlags: true}
+	assert.True(t, opts.HasPage(debugCmd))
+	page := BuildPage(debugCmd, opts)
+	assert.True(t, page.Hidden)
+	assert.Equal(t, []ManFlag{
+		{Name: "quiet", NoOptDefVal: "true", DefValue: "false", Usage: "say less", Type: "bool"},
+		{Name: "trace", NoOptDefVal: "true", DefValue: "false", Usage: "trace everything", Type: "bool", Hidden: true},
+	}, page.AllFlags)
+	assert.Equal(t, []SeeAlso{{CmdPath: "foo debug", Section: "1", IsChild: true, Hidden: true}}, BuildPage(appCmd, opts).SeeAlsos)
+
+	// A hidden command without a Run is still documented for its children
+	internalCmd := &cobra.Command{Use: "internal", Hidden: true}
+	internalCmd.AddCommand(&cobra.Command{Use: "reset", Hidden: true, Run: func(cmd *cobra.Command, args []string) {}})
+	appCmd.AddCommand(internalCmd)
+	files, err := ListDocs(appCmd, opts, "", "troff")
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	for templateName, expected := range map[string][]string{
+		"troff":    {"foo\\-debug - debug things (internal)\n", "\n(internal) trace everything\n", "\nsay less\n"},
+		"mdoc":     {".Nd debug things (internal)\n", "\n(internal) trace everything\n", "\nsay less\n"},
+		"markdown": {"## foo debug (internal)\n", "* --trace - (internal) trace everything\n", "* --quiet - say less\n"},
+	} {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, GenerateOnePage(debugCmd, opts, templateName, buf))
+		for _, text := range expected {
+			assert.Contains(t, buf.String(), text, templateName)
+		}
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(appCmd, opts, "markdown", buf))
+	assert.Contains(t, buf.String(), "* [foo debug](foo_debug.md) (internal)\n")
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(appCmd, opts, "troff", buf))
+	assert.Contains(t, buf.String(), ".BR foo\\-debug (1)\\ (internal)\n")
+	assert.Contains(t, buf.String(), "\\fBfoo debug\\fR (internal) [ flags ]\n")
+	formatted := string(stripOverstrike(formatRoff(buf.String(), 60)))
+	assert.Contains(t, formatted, "foo-debug(1) (internal)")
+	assert.Contains(t, formatted, "foo debug (internal) [ flags ]")
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(appCmd, opts, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Xr foo debug 1 ( internal )")
+	assert.Contains(t, buf.String(), ".Nm foo debug Li ( internal ) Op Fl flags Op args\n")
+	formatted = string(stripOverstrike(formatRoff(buf.String(), 60)))
+	assert.Contains(t, formatted, "foo debug (internal) [-flags [args]]")
+
+	// Hidden flags are marked in the SYNOPSIS too
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(debugCmd, opts, "troff", buf))
+	assert.Contains(t, buf.String(), "[\\fI\\-\\-trace\\fP\\ (internal)] ")
+	assert.Contains(t, string(stripOverstrike(formatRoff(buf.String(), 60))), "[--trace (internal)]")
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(debugCmd, opts, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Op Fl \\-trace Li ( internal )\n")
+	assert.Contains(t, string(stripOverstrike(formatRoff(buf.String(), 60))), "[--trace (internal)]")
+}
+
+func TestIncludeDeprecated(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.Flags().StringP("old", "o", "", "the old way")
+	appCmd.Flags().MarkDeprecated("old", "use --new instead")
+	appCmd.Flags().BoolP("verbose", "V", false, "be loud")
+	appCmd.Flags().MarkShorthandDeprecated("verbose", "use -v instead")
+	legacyCmd := &cobra.Command{Use: "legacy", Deprecated: "use foo new", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(legacyCmd)
+
+	opts := &CobraManOptions{}
+	assert.False(t, opts.HasPage(legacyCmd))
+	page := BuildPage(appCmd, opts)
+	assert.Empty(t, page.DeprecatedFlags)
+	assert.Empty(t, page.DeprecatedCommands)
+	assert.Equal(t, "use foo new", BuildPage(legacyCmd, opts).Deprecated)
+
+	opts = &CobraManOptions{IncludeDeprecated: true}
+	assert.True(t, opts.HasPage(legacyCmd))
+	page = BuildPage(appCmd, opts)
+	assert.Equal(t, []ManFlag{{Name: "verbose", NoOptDefVal: "true", DefValue: "false", Usage: "be loud", Type: "bool"}}, page.AllFlags)
+	assert.Equal(t, []ManFlag{
+		{Shorthand: "o", Name: "old", DefValue: "", Usage: "the old way", Type: "string", Placeholder: "string", Deprecated: "use --new instead"},
+		{Shorthand: "V", Name: "verbose", NoOptDefVal: "true", DefValue: "false", Usage: "be loud", Type: "bool", ShorthandDeprecated: "use -v instead"},
+	}, page.DeprecatedFlags)
+	assert.Equal(t, []SeeAlso{{CmdPath: "foo legacy", Section: "1", IsChild: true, Deprecated: "use foo new"}}, page.DeprecatedCommands)
+
+	for templateName, expected := range map[string][]string{
+		"troff": {".SH DEPRECATED\n.TP\n\\fBfoo legacy\\fP\nuse foo new\n",
+			".TP\n\\fB\\-o\\fP, \\fB\\-\\-old\\fP\nuse \\-\\-new instead\n",
+			".TP\n\\fB\\-V\\fP\nShorthand of \\fB\\-\\-verbose\\fP: use \\-v instead\n",
+			"\\fBfoo legacy\\fR (deprecated) [ flags ]\n", ".BR foo\\-legacy (1)\\ (deprecated)\n"},
+		"mdoc": {".Sh DEPRECATED\n.Bl -tag -width Ds\n.It Cm foo legacy\nuse foo new\n",
+			".It Fl o, Fl \\-old\nuse \\-\\-new instead\n",
+			".It Fl V\nShorthand of\n.Fl \\-verbose :\nuse \\-v instead\n.El\n",
+			".Nm foo legacy Li ( deprecated ) Op Fl flags Op args\n", ".Xr foo legacy 1 ( deprecated )"},
+		"markdown": {"### Deprecated\n\n* [foo legacy](foo_legacy.md) - use foo new\n",
+			"* -o, --old - use --new instead\n",
+			"* -V - Shorthand of --verbose: use -v instead\n",
+			"* [foo legacy](foo_legacy.md) (deprecated)\n"},
+	} {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, GenerateOnePage(appCmd, opts, templateName, buf))
+		for _, text := range expected {
+			assert.Contains(t, buf.String(), text, templateName)
+		}
+		buf.Reset()
+		assert.NoError(t, GenerateOnePage(legacyCmd, opts, templateName, buf))
+		assert.Contains(t, buf.String(), "This command is deprecated: use foo new\n", templateName)
+	}
+}
+
+func TestHelpTopics(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	appCmd.PersistentFlags().Bool("verbose", false, "be loud")
+	envCmd := &cobra.Command{Use: "environment", Short: "environment variables", Long: "FOO_HOME sets the home."}
+	appCmd.AddCommand(envCmd, &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}})
+
+	opts := &CobraManOptions{ExcludeHelpTopics: true}
+	assert.False(t, opts.HasPage(envCmd))
+	assert.Len(t, BuildPage(appCmd, opts).SeeAlsos, 1)
+
+	opts = &CobraManOptions{}
+	assert.True(t, opts.HasPage(envCmd))
+	page := BuildPage(appCmd, opts)
+	assert.Equal(t, "1", page.Section)
+	assert.Equal(t, []SeeAlso{{CmdPath: "foo bar", Section: "1", IsChild: true}}, page.SubCommands)
+	assert.Equal(t, []SeeAlso{
+		{CmdPath: "foo bar", Section: "1", IsChild: true},
+		{CmdPath: "foo environment", Section: "7", IsChild: true, IsHelpTopic: true},
+	}, page.SeeAlsos)
+
+	page = BuildPage(envCmd, opts)
+	assert.True(t, page.IsHelpTopic)
+	assert.Equal(t, "7", page.Section)
+	assert.Empty(t, page.AllFlags)
+	assert.Equal(t, "1", page.SeeAlsos[0].Section)
+
+	opts = &CobraManOptions{HelpTopicSection: "5", SectionDirectories: true}
+	docs, err := ListDocs(appCmd, opts, "", "troff")
+	assert.NoError(t, err)
+	assert.Equal(t, []DocFile{
+		{Path: "man1/foo.1", CommandPath: "foo"},
+		{Path: "man1/foo-bar.1", CommandPath: "foo bar"},
+		{Path: "man5/foo-environment.5", CommandPath: "foo environment"},
+	}, docs)
+	docs, err = ListDocs(appCmd, opts, "", "markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, "man5/foo_environment.md", docs[2].Path)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(envCmd, opts, "troff", buf))
+	assert.Regexp(t, "^\\.TH \"FOO\\\\-ENVIRONMENT\" \"5\"", buf.String())
+	assert.Contains(t, buf.String(), ".SH NAME\nfoo\\-environment - environment variables\n.SH DESCRIPTION\n.PP\nFOO\\_HOME sets the home.\n")
+	assert.NotContains(t, buf.String(), "OPTIONS")
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(envCmd, opts, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".Nd environment variables\n.Sh DESCRIPTION\n")
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(appCmd, opts, "troff", buf))
+	assert.Contains(t, buf.String(), ".BR foo\\-environment (5)\n")
+}
+
+func TestFlagPlaceholders(t *testing.T) {
+	cmd := &cobra.Command{Use: "foo", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.Flags().Duration("timeout", 0, "how long to wait")
+	cmd.Flags().StringSlice("tag", nil, "tags to add")
+	cmd.Flags().String("config", "", "read the config from `path`")
+	cmd.Flags().Int("count", 1, "how many `times`")
+	cmd.Flags().Bool("force", false, "overwrite")
+	assert.NoError(t, cmd.Flags().SetAnnotation("count", "man-arg-hints", []string{"n"}))
+	cmd.Flags().Duration("wait", time.Minute, "how long to sleep")
+	cmd.Flags().String("name", "joe", "name to use")
+	cmd.Flags().StringSlice("group", []string{"a", "b"}, "groups to join")
+
+	defaults := map[string]string{}
+	for _, flag := range BuildPage(cmd, &CobraManOptions{}).AllFlags {
+		defaults[flag.Name] = flag.Default
+	}
+	assert.Equal(t, map[string]string{"timeout": "", "tag": "", "config": "", "count": "1", "force": "",
+		"wait": "1m0s", "name": `"joe"`, "group": "[a,b]"}, defaults)
+
+	placeholders := map[string]string{}
+	for _, flag := range BuildPage(cmd, &CobraManOptions{}).AllFlags {
+		placeholders[flag.Name] = flag.Type + " " + flag.Placeholder + ": " + flag.Usage
+	}
+	assert.Equal(t, map[string]string{
+		"config":  "string path: read the config from path",
+		"count":   "int n: how many times",
+		"force":   "bool : overwrite",
+		"tag":     "stringSlice strings: tags to add",
+		"timeout": "duration duration: how long to wait",
+		"wait":    "duration duration: how long to sleep",
+		"name":    "string string: name to use",
+		"group":   "stringSlice strings: groups to join",
+	}, placeholders)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "troff", buf))
+	assert.Contains(t, buf.String(), ".TP\n\\fB\\-\\-timeout\\fP = <duration>\nhow long to wait\n")
+	assert.Contains(t, buf.String(), ".TP\n\\fB\\-\\-force\\fP\noverwrite\n")
+	assert.Contains(t, buf.String(), ".TP\n\\fB\\-\\-count\\fP = <n>\nhow many times (default 1)\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	assert.Contains(t, buf.String(), ".It Fl \\-config Ar  path\nread the config from path\n")
+	assert.Contains(t, buf.String(), "\nhow many times (default 1)\n")
+
+	buf.Reset()
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "markdown", buf))
+	assert.Contains(t, buf.String(), "* --tag=<strings> - tags to add\n")
+	assert.Contains(t, buf.String(), "* --count=<n> - how many times (default 1)\n")
+}
+
+func TestOptionsLeaveOutPersistentFlags(t *testing.T) {
+	appCmd := &cobra.Command{Use: "app", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.PersistentFlags().String("config", "", "config file")
+	remoteCmd := &cobra.Command{Use: "remote"}
+	addCmd := &cobra.Command{Use: "add", Run: func(cmd *cobra.Command, args []string) {}}
+	addCmd.Flags().String("name", "", "the name")
+	addCmd.PersistentFlags().Bool("dry", false, "dry run")
+	appCmd.AddCommand(remoteCmd)
+	remoteCmd.AddCommand(addCmd)
+
+	// The page is the same before and after cobra merged the persistent flags
+	for i := 0; i < 2; i++ {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, GenerateOnePage(addCmd, &CobraManOptions{}, "troff", buf))
+		assert.Contains(t, buf.String(), "\\fBapp remote add \\fR[\\fI\\-\\-name\\fP] [<args>]\n")
+		assert.Contains(t, buf.String(), ".SH OPTIONS\n.TP\n\\fB\\-\\-name\\fP = <string>\nthe name\n\n.SH")
+	}
+}
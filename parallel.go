@@ -0,0 +1,98 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// PageErrors is returned by GenerateDocs when pages fail to generate with
+// Parallelism set.  Every page is still attempted and the errors are in the
+// order the pages would have been generated in one at a time.
+type PageErrors []error
+
+func (e PageErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// forEachPage calls fn for every page in files.  With opts.Parallelism set
+// a pool of that many workers is used, every page is attempted and the
+// errors are returned as PageErrors.  Otherwise the first error stops it.
+// Each worker passes fn its own copy of opts.
+func forEachPage(files []pageFile, opts *CobraManOptions, fn func(n int, opts *CobraManOptions) error) error {
+	if opts.Parallelism <= 1 {
+		for n := range files {
+			if err := fn(n, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, pf := range files {
+		prepareFlags(pf.cmd)
+	}
+
+	workers := opts.Parallelism
+	if workers > len(files) {
+		workers = len(files)
+	}
+	errs := make([]error, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// GenerateOnePage sets defaults in the opts it is given
+			workerOpts := *opts
+			for n := range next {
+				errs[n] = fn(n, &workerOpts)
+			}
+		}()
+	}
+	for n := range files {
+		next <- n
+	}
+	close(next)
+	wg.Wait()
+
+	var pageErrs PageErrors
+	for _, err := range errs {
+		if err != nil {
+			pageErrs = append(pageErrs, err)
+		}
+	}
+	if len(pageErrs) > 0 {
+		return pageErrs
+	}
+	return nil
+}
+
+// prepareFlags has cobra build the merged flag sets of cmd.  cobra builds
+// and sorts them the first time they are asked for, which is not safe to do
+// from more than one goroutine.  Once built, asking for them only reads.
+func prepareFlags(cmd *cobra.Command) {
+	for _, flags := range []*pflag.FlagSet{cmd.InheritedFlags(), cmd.LocalFlags(), cmd.Flags(), cmd.PersistentFlags()} {
+		flags.VisitAll(func(*pflag.Flag) {})
+	}
+}
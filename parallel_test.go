@@ -0,0 +1,87 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func parallelTestCmd() *cobra.Command {
+	appCmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	appCmd.PersistentFlags().Bool("verbose", false, "be loud")
+	for i := 0; i < 10; i++ {
+		groupCmd := &cobra.Command{Use: fmt.Sprintf("group%d", i), Short: "a group"}
+		groupCmd.PersistentFlags().String("region", "", "region to use")
+		for j := 0; j < 10; j++ {
+			cmd := &cobra.Command{Use: fmt.Sprintf("cmd%d", j), Short: "a command", Run: func(cmd *cobra.Command, args []string) {}}
+			cmd.Flags().IntP("count", "c", j, "how many")
+			groupCmd.AddCommand(cmd)
+		}
+		appCmd.AddCommand(groupCmd)
+	}
+	return appCmd
+}
+
+func TestParallelism(t *testing.T) {
+	serialDir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(serialDir)
+	parallelDir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(parallelDir)
+
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	opts := CobraManOptions{Date: &date}
+	assert.NoError(t, GenerateDocs(parallelTestCmd(), &opts, serialDir, "troff"))
+	opts = CobraManOptions{Date: &date, Parallelism: 8}
+	assert.NoError(t, GenerateDocs(parallelTestCmd(), &opts, parallelDir, "troff"))
+
+	files, _ := filepath.Glob(filepath.Join(serialDir, "*"))
+	assert.Len(t, files, 111)
+	for _, file := range files {
+		serial, _ := ioutil.ReadFile(file)
+		parallel, err := ioutil.ReadFile(filepath.Join(parallelDir, filepath.Base(file)))
+		assert.NoError(t, err)
+		assert.Equal(t, string(serial), string(parallel), file)
+	}
+}
+
+func TestParallelismErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	RegisterTemplate("fails-for-cmd3", "-", "txt", `{{ if eq .CommandPath "foo group2 cmd3" "foo group7 cmd3" }}{{ template "nope" }}{{ end }}`)
+	for i := 0; i < 5; i++ {
+		opts := CobraManOptions{Parallelism: 4}
+		err = GenerateDocs(parallelTestCmd(), &opts, dir, "fails-for-cmd3")
+		if assert.IsType(t, PageErrors{}, err) {
+			errs := err.(PageErrors)
+			assert.Len(t, errs, 2)
+			assert.Contains(t, errs[0].Error(), "nope")
+			assert.Equal(t, errs[0].Error()+"\n"+errs[1].Error(), err.Error())
+		}
+	}
+	// Every other page is still written
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	assert.Len(t, files, 111)
+}
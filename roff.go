@@ -0,0 +1,672 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// This is a small formatter for the subset of the man and mdoc macros used by
+// the built-in templates.  It lays out a page much like man(1) does in a
+// terminal so pages can be looked at without groff being installed.  Bold and
+// underlined text use the backspace overstrike sequences understood by less.
+// Requests and macros it does not know are ignored.  Pull requests welcome!
+
+// RenderTerminalPage generates the page for cmd with a man template (such as
+// "troff" or "mdoc") and formats it for a terminal that is width columns wide.
+func RenderTerminalPage(cmd *cobra.Command, opts *CobraManOptions, templateName string, width int, w io.Writer) error {
+	buf := new(bytes.Buffer)
+	if err := GenerateOnePage(cmd, opts, templateName, buf); err != nil {
+		return err
+	}
+	_, err := w.Write(formatRoff(buf.String(), width))
+	return err
+}
+
+var overstrikeRegex = regexp.MustCompile(".\x08")
+
+// stripOverstrike removes the bold and underline sequences from formatted text.
+func stripOverstrike(b []byte) []byte {
+	return overstrikeRegex.ReplaceAll(b, nil)
+}
+
+const (
+	styleRoman = iota
+	styleBold
+	styleItalic
+)
+
+const (
+	bodyIndent = 7
+	nbsp       = '\u00a0' // an unbreakable space from \~
+)
+
+type cell struct {
+	r     rune
+	style int
+}
+
+type word []cell
+
+type roffWriter struct {
+	width  int
+	indent int
+	out    *bytes.Buffer
+
+	words     []word
+	nextStyle int  // font for the next text line set by .B or .I without args
+	noFill    bool // between .nf and .fi
+	blank     bool // the last line written was blank
+	expectTag bool // the next text line is the tag of a .TP
+
+	title, section                         string
+	leftFooter, centerFooter, centerHeader string
+	name                                   string // set by the first .Nm
+	inSynopsis                             bool
+	listIndents                            []int
+}
+
+func formatRoff(page string, width int) []byte {
+	if width < 2*bodyIndent {
+		width = 2 * bodyIndent
+	}
+	rw := &roffWriter{width: width, out: new(bytes.Buffer), blank: true, nextStyle: -1}
+	for _, line := range strings.Split(page, "\n") {
+		rw.line(line)
+	}
+	rw.finish()
+	return rw.out.Bytes()
+}
+
+func (rw *roffWriter) line(line string) {
+	if line == "" {
+		rw.paragraph()
+		return
+	}
+	if line[0] != '.' && line[0] != '\'' {
+		if rw.noFill {
+			rw.flush()
+			rw.addText(line)
+			rw.flushLine(rw.words)
+			rw.words = nil
+			return
+		}
+		if line[0] == ' ' {
+			rw.flush()
+		}
+		if rw.nextStyle >= 0 {
+			rw.addStyled(line, rw.nextStyle)
+			rw.nextStyle = -1
+		} else {
+			rw.addText(line)
+		}
+		rw.tagDone()
+		return
+	}
+
+	name, args := parseRequest(line[1:])
+	switch name {
+	// man macros
+	case "TH":
+		rw.title, rw.section = arg(args, 0), arg(args, 1)
+		rw.centerFooter, rw.leftFooter, rw.centerHeader = arg(args, 2), arg(args, 3), arg(args, 4)
+		rw.header()
+	case "SH", "Sh":
+		rw.heading(strings.Join(args, " "), 0)
+		rw.inSynopsis = strings.Join(args, " ") == "SYNOPSIS"
+	case "SS", "Ss":
+		rw.heading(strings.Join(args, " "), 3)
+	case "PP", "LP", "P", "Pp":
+		rw.paragraph()
+	case "sp":
+		rw.flush()
+		if !rw.blank {
+			rw.blankLine()
+		}
+	case "br":
+		rw.flush()
+	case "TP":
+		rw.paragraph()
+		rw.expectTag = true
+	case "IP":
+		rw.paragraph()
+		if len(args) > 0 {
+			rw.expectTag = true
+			rw.addText(args[0])
+			rw.tagDone()
+		} else {
+			rw.indent = bodyIndent * 2
+		}
+	case "RS":
+		rw.flush()
+		rw.indent += bodyIndent
+	case "RE":
+		rw.flush()
+		if rw.indent > bodyIndent {
+			rw.indent -= bodyIndent
+		}
+	case "nf":
+		rw.flush()
+		rw.noFill = true
+	case "fi":
+		rw.noFill = false
+	case "B", "I":
+		style := styleBold
+		if name == "I" {
+			style = styleItalic
+		}
+		if len(args) == 0 {
+			rw.nextStyle = style
+			return
+		}
+		rw.addStyled(strings.Join(args, " "), style)
+		rw.tagDone()
+	case "BR", "BI", "IB", "IR", "RB", "RI":
+		rw.alternate(name, args)
+		rw.tagDone()
+	case "SM":
+		rw.addText(strings.Join(args, " "))
+		rw.tagDone()
+
+	// mdoc macros
+	case "Dd":
+		rw.centerFooter = strings.Join(args, " ")
+	case "Dt":
+		rw.title, rw.section = arg(args, 0), arg(args, 1)
+		rw.centerHeader = arg(args, 2)
+		rw.header()
+	case "Bl":
+		rw.flush()
+		rw.listIndents = append(rw.listIndents, rw.indent)
+	case "El":
+		rw.flush()
+		if n := len(rw.listIndents); n > 0 {
+			rw.indent = rw.listIndents[n-1]
+			rw.listIndents = rw.listIndents[:n-1]
+		}
+	case "It":
+		rw.flush()
+		if n := len(rw.listIndents); n > 0 {
+			rw.indent = rw.listIndents[n-1]
+		}
+		rw.expectTag = true
+		rw.words = append(rw.words, rw.mdoc(name, args)...)
+		rw.tagDone()
+	case "Nm":
+		if rw.inSynopsis {
+			rw.flush()
+		}
+		fallthrough
+	case "Nd", "Op", "Brq", "Fl", "Ar", "Xr", "Cm", "Pa", "Em", "Sy", "Li", "Ql", "Ev":
+		rw.words = append(rw.words, rw.mdoc(name, args)...)
+		rw.tagDone()
+	}
+}
+
+// parseRequest splits a request or macro line into its name and arguments.
+// Comments come back with an empty name.
+func parseRequest(line string) (string, []string) {
+	if strings.HasPrefix(line, `\"`) || strings.HasPrefix(line, `"`) {
+		return "", nil
+	}
+	fields := splitArgs(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// splitArgs splits a line on spaces while keeping "quoted strings" together.
+func splitArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	inQuote, started := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"' && inQuote && i+1 < len(line) && line[i+1] == '"':
+			current.WriteByte('"')
+			i++
+		case c == '"':
+			inQuote = !inQuote
+			started = true
+		case c == ' ' && !inQuote:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		case c == '\\' && i+1 < len(line):
+			current.WriteByte(c)
+			current.WriteByte(line[i+1])
+			started = true
+			i++
+		default:
+			current.WriteByte(c)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return plainText(args[i])
+	}
+	return ""
+}
+
+// plainText resolves escapes and drops any font changes.
+func plainText(s string) string {
+	var b strings.Builder
+	for _, c := range parseEscapes(s, styleRoman) {
+		if c.r == nbsp {
+			c.r = ' '
+		}
+		b.WriteRune(c.r)
+	}
+	return b.String()
+}
+
+// parseEscapes turns text with troff escapes into cells.  Font escapes such
+// as \fB change the style of the cells that follow.
+func parseEscapes(s string, style int) []cell {
+	cells := make([]cell, 0, len(s))
+	prev := style
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if r != '\\' || i >= len(s) {
+			cells = append(cells, cell{r, style})
+			continue
+		}
+		esc := s[i]
+		i++
+		switch esc {
+		case 'f':
+			if i < len(s) {
+				font := s[i]
+				i++
+				switch font {
+				case 'B':
+					prev, style = style, styleBold
+				case 'I':
+					prev, style = style, styleItalic
+				case 'R':
+					prev, style = style, styleRoman
+				case 'P':
+					prev, style = style, prev
+				}
+			}
+		case '(':
+			// Special characters are two letters long - show the common ones
+			if i+2 <= len(s) {
+				switch s[i : i+2] {
+				case "em", "en", "hy":
+					cells = append(cells, cell{'-', style})
+				case "bu":
+					cells = append(cells, cell{'o', style})
+				}
+				i += 2
+			}
+		case 'e', '\\':
+			cells = append(cells, cell{'\\', style})
+		case '~', ' ':
+			cells = append(cells, cell{nbsp, style})
+		case '&', '%', 'c':
+			// zero width
+		default:
+			cells = append(cells, cell{rune(esc), style})
+		}
+	}
+	return cells
+}
+
+// splitWords breaks cells into words at (breakable) spaces.
+func splitWords(cells []cell) []word {
+	var words []word
+	var current word
+	for _, c := range cells {
+		if c.r == ' ' || c.r == '\t' {
+			if len(current) > 0 {
+				words = append(words, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, c)
+	}
+	if len(current) > 0 {
+		words = append(words, current)
+	}
+	return words
+}
+
+func styled(s string, style int) word {
+	cells := make(word, 0, len(s))
+	for _, c := range parseEscapes(s, style) {
+		if c.style == styleRoman {
+			c.style = style
+		}
+		cells = append(cells, c)
+	}
+	return cells
+}
+
+func (rw *roffWriter) addText(s string) {
+	rw.words = append(rw.words, splitWords(parseEscapes(s, styleRoman))...)
+}
+
+func (rw *roffWriter) addStyled(s string, style int) {
+	rw.words = append(rw.words, splitWords(styled(s, style))...)
+}
+
+// alternate handles .BR and friends which alternate between two fonts
+// without spaces between the arguments.
+func (rw *roffWriter) alternate(name string, args []string) {
+	styles := map[byte]int{'B': styleBold, 'I': styleItalic, 'R': styleRoman}
+	var cells []cell
+	for i, a := range args {
+		cells = append(cells, styled(a, styles[name[i%2]])...)
+	}
+	rw.words = append(rw.words, splitWords(cells)...)
+}
+
+// mdoc formats the words of a line of mdoc macros.  Macros on the line can
+// call further macros, e.g. ".Op Fl v".
+func (rw *roffWriter) mdoc(name string, args []string) []word {
+	var words []word
+	glue := false // the next word is attached to the previous one
+	add := func(w word, glueLeft bool) {
+		if len(w) == 0 {
+			return
+		}
+		if (glue || glueLeft) && len(words) > 0 {
+			words[len(words)-1] = append(words[len(words)-1], w...)
+		} else {
+			words = append(words, w)
+		}
+		glue = false
+	}
+
+	tokens := append([]string{name}, args...)
+	switch name {
+	case "It":
+		tokens = args
+	case "Nd":
+		add(word{{'-', styleRoman}}, false)
+		tokens = args
+	}
+
+	var closers []rune // closes the brackets opened on the line
+	macro := ""        // formats the plain words that follow it
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if !isCallable(tok) {
+			switch tok {
+			case ".", ",", ";", ":", "?", "!", ")", "]":
+				add(styled(tok, styleRoman), true)
+				macro = ""
+			case "(", "[":
+				add(styled(tok, styleRoman), false)
+				glue = true
+				macro = ""
+			case "|":
+				add(styled(tok, styleRoman), false)
+			default:
+				add(rw.mdocArg(macro, tok), false)
+			}
+			continue
+		}
+
+		macro = tok
+		hasArgs := i+1 < len(tokens) && !isCallable(tokens[i+1]) && !isDelimiter(tokens[i+1])
+		switch tok {
+		case "Op":
+			add(word{{'[', styleRoman}}, false)
+			glue = true
+			closers = append(closers, ']')
+			macro = ""
+		case "Brq":
+			add(word{{'{', styleRoman}}, false)
+			glue = true
+			closers = append(closers, '}')
+			macro = ""
+		case "Ns":
+			glue = true
+			macro = ""
+		case "Nm":
+			if hasArgs && rw.name == "" {
+				rw.name = plainText(tokens[i+1])
+			}
+			if !hasArgs {
+				add(styled(rw.name, styleBold), false)
+			}
+		case "Fl":
+			if !hasArgs {
+				add(styled("-", styleBold), false)
+			}
+		case "Ar":
+			if !hasArgs {
+				add(styled("file", styleItalic), false)
+				add(styled("...", styleRoman), false)
+			}
+		case "Xr":
+			if hasArgs {
+				w := styled(tokens[i+1], styleBold)
+				i++
+				if i+1 < len(tokens) && !isCallable(tokens[i+1]) && !isDelimiter(tokens[i+1]) {
+					w = append(w, styled("("+tokens[i+1]+")", styleRoman)...)
+					i++
+				}
+				add(w, false)
+			}
+			macro = ""
+		}
+	}
+	for n := len(closers) - 1; n >= 0; n-- {
+		add(word{{closers[n], styleRoman}}, true)
+	}
+	return words
+}
+
+// mdocArg formats an argument of a callable mdoc macro.
+func (rw *roffWriter) mdocArg(macro string, arg string) word {
+	switch macro {
+	case "Fl":
+		return styled("-"+arg, styleBold)
+	case "Nm", "Cm", "Sy":
+		return styled(arg, styleBold)
+	case "Ar", "Pa", "Em":
+		return styled(arg, styleItalic)
+	}
+	return styled(arg, styleRoman)
+}
+
+func isCallable(s string) bool {
+	switch s {
+	case "Op", "Brq", "Fl", "Ar", "Nm", "Xr", "Ns", "Cm", "Pa", "Em", "Sy", "Li", "Ql", "Ev":
+		return true
+	}
+	return false
+}
+
+func isDelimiter(s string) bool {
+	switch s {
+	case ".", ",", ";", ":", "?", "!", ")", "]", "(", "[", "|":
+		return true
+	}
+	return false
+}
+
+// tagDone finishes the tag of a .TP or .It once its text has been added.
+func (rw *roffWriter) tagDone() {
+	if !rw.expectTag {
+		return
+	}
+	rw.expectTag = false
+	base := rw.indent
+	if base < bodyIndent {
+		base = bodyIndent
+	}
+	rw.indent = base
+	rw.flush()
+	rw.indent = base + bodyIndent
+}
+
+func (rw *roffWriter) heading(text string, indent int) {
+	rw.flush()
+	if !rw.blank {
+		rw.blankLine()
+	}
+	rw.indent = indent
+	rw.addStyled(text, styleBold)
+	rw.flush()
+	rw.indent = bodyIndent
+	rw.listIndents = nil
+	// No blank line between a heading and its first paragraph
+	rw.blank = true
+}
+
+func (rw *roffWriter) paragraph() {
+	rw.flush()
+	if !rw.blank {
+		rw.blankLine()
+	}
+	if len(rw.listIndents) == 0 {
+		rw.indent = bodyIndent
+	}
+}
+
+func (rw *roffWriter) header() {
+	page := rw.title
+	if rw.section != "" {
+		page += "(" + rw.section + ")"
+	}
+	center := rw.centerHeader
+	if center == "" {
+		center = sectionNames[rw.section]
+	}
+	rw.writeLine(threePart(page, center, page, rw.width))
+	rw.blankLine()
+}
+
+var sectionNames = map[string]string{
+	"1": "General Commands Manual",
+	"5": "File Formats Manual",
+	"7": "Miscellaneous Information Manual",
+	"8": "System Manager's Manual",
+}
+
+func (rw *roffWriter) finish() {
+	rw.flush()
+	if rw.title == "" {
+		return
+	}
+	if !rw.blank {
+		rw.blankLine()
+	}
+	page := rw.title
+	if rw.section != "" {
+		page += "(" + rw.section + ")"
+	}
+	rw.writeLine(threePart(rw.leftFooter, rw.centerFooter, page, rw.width))
+}
+
+// threePart lays out a header or footer line with parts on the left,
+// in the center and on the right.
+func threePart(left, center, right string, width int) string {
+	used := utf8.RuneCountInString(left) + utf8.RuneCountInString(center) + utf8.RuneCountInString(right)
+	if used+2 >= width {
+		return strings.TrimSpace(left + "  " + center + "  " + right)
+	}
+	leftGap := (width-utf8.RuneCountInString(center))/2 - utf8.RuneCountInString(left)
+	if leftGap < 1 {
+		leftGap = 1
+	}
+	rightGap := width - used - leftGap
+	if rightGap < 1 {
+		rightGap = 1
+	}
+	return left + strings.Repeat(" ", leftGap) + center + strings.Repeat(" ", rightGap) + right
+}
+
+// flush fills the pending words into lines that fit the page width.
+func (rw *roffWriter) flush() {
+	if len(rw.words) == 0 {
+		return
+	}
+	available := rw.width - rw.indent
+	var line []word
+	length := 0
+	for _, w := range rw.words {
+		if len(line) > 0 && length+1+len(w) > available {
+			rw.flushLine(line)
+			line, length = nil, 0
+		}
+		if len(line) > 0 {
+			length++
+		}
+		line = append(line, w)
+		length += len(w)
+	}
+	rw.flushLine(line)
+	rw.words = nil
+}
+
+func (rw *roffWriter) flushLine(line []word) {
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", rw.indent))
+	for i, w := range line {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		for _, c := range w {
+			switch {
+			case c.r == nbsp:
+				b.WriteByte(' ')
+			case c.style == styleBold:
+				b.WriteRune(c.r)
+				b.WriteByte('\b')
+				b.WriteRune(c.r)
+			case c.style == styleItalic:
+				b.WriteString("_\b")
+				b.WriteRune(c.r)
+			default:
+				b.WriteRune(c.r)
+			}
+		}
+	}
+	rw.writeLine(b.String())
+}
+
+func (rw *roffWriter) writeLine(s string) {
+	rw.out.WriteString(trimRightSpace(s))
+	rw.out.WriteByte('\n')
+	rw.blank = false
+}
+
+func (rw *roffWriter) blankLine() {
+	rw.out.WriteByte('\n')
+	rw.blank = true
+}
@@ -0,0 +1,138 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRoff(t *testing.T) {
+	page := `.TH "FOO" "1" "Jun 1968" "Foo 1.0" "Foo Manual"
+.\" a comment
+.SH NAME
+foo \- do \fBbold\fP and \fIitalic\fR things
+.SH OPTIONS
+.TP
+\fB\-\-flag\fP
+Some usage
+.SH SEE ALSO
+.BR foo\-bar (1)
+`
+	out := string(formatRoff(page, 40))
+	assert.Equal(t, "foo - do b\bbo\bol\bld\bd and _\bi_\bt_\ba_\bl_\bi_\bc things", strings.Split(out, "\n")[3][7:])
+
+	plain := string(stripOverstrike([]byte(out)))
+	assert.Equal(t, `FOO(1)         Foo Manual         FOO(1)
+
+NAME
+       foo - do bold and italic things
+
+OPTIONS
+       --flag
+              Some usage
+
+SEE ALSO
+       foo-bar(1)
+
+Foo 1.0         Jun 1968          FOO(1)
+`, plain)
+}
+
+func TestFormatRoffWrapping(t *testing.T) {
+	page := ".SH DESCRIPTION\n.PP\none two three four five six seven eight nine ten\n.PP\neleven\\~twelve"
+	out := string(stripOverstrike(formatRoff(page, 30)))
+	assert.Equal(t, `DESCRIPTION
+       one two three four five
+       six seven eight nine
+       ten
+
+       eleven twelve
+`, out)
+}
+
+func TestFormatMdoc(t *testing.T) {
+	page := `.Dd June 1968
+.Dt FOO 1
+.Sh NAME
+.Nm foo
+.Nd make things
+.Sh SYNOPSIS
+.Nm foo bar Op Fl flags Op args
+.Nm foo
+.Op Fl v | \-verbose
+.Sh DESCRIPTION
+.Nm
+does things.
+.Bl -tag -width Ds -compact
+.Pp
+.It Fl v , Fl \-verbose Ar level
+Be loud
+.El
+.Sh SEE ALSO
+.Xr foo\-bar 1
+`
+	plain := string(stripOverstrike(formatRoff(page, 50)))
+	assert.Equal(t, `FOO(1)       General Commands Manual        FOO(1)
+
+NAME
+       foo - make things
+
+SYNOPSIS
+       foo bar [-flags [args]]
+       foo [-v | --verbose]
+
+DESCRIPTION
+       foo does things.
+
+       -v, --verbose level
+              Be loud
+
+SEE ALSO
+       foo-bar(1)
+
+                    June 1968               FOO(1)
+`, plain)
+}
+
+func TestRenderTerminalPage(t *testing.T) {
+	cmd := &cobra.Command{Use: "foo", Short: "a short one"}
+	cmd.Flags().String("thing", "", "string with no default")
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+
+	for _, templateName := range []string{"troff", "mdoc"} {
+		opts := CobraManOptions{Date: &date}
+		buf := new(bytes.Buffer)
+		assert.NoError(t, RenderTerminalPage(cmd, &opts, templateName, 60, buf))
+		plain := string(stripOverstrike(buf.Bytes()))
+		assert.Regexp(t, "^FOO\\(1\\) +General Commands Manual +FOO\\(1\\)\\n", plain, templateName)
+		assert.Regexp(t, "NAME\\n       foo - a short one\\n", plain, templateName)
+		assert.Regexp(t, "\\n       --thing", plain, templateName)
+		assert.Regexp(t, "Jun(e)? 1968 +FOO\\(1\\)\\n$", plain, templateName)
+	}
+}
+
+func TestFormatMdocChoices(t *testing.T) {
+	cmd := &cobra.Command{Use: "push", Args: cobra.RangeArgs(1, 3), ValidArgs: []string{"origin", "upstream"},
+		Run: func(cmd *cobra.Command, args []string) {}}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &CobraManOptions{}, "mdoc", buf))
+	plain := string(stripOverstrike(formatRoff(buf.String(), 60)))
+	assert.Contains(t, plain, "SYNOPSIS\n       push {origin | upstream} [arg [arg]]\n")
+}
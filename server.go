@@ -0,0 +1,217 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type docHandler struct {
+	cmd          *cobra.Command
+	opts         CobraManOptions
+	templateName string
+
+	// lock serializes the requests as cobra sorts the commands and merges
+	// the flags of the command tree the first time they are asked for
+	lock sync.Mutex
+}
+
+// NewDocHandler returns an http.Handler that generates the docs for the
+// passed in cobra.Command and its children when they are requested.  Each
+// page is served under the same path GenerateDocs would write it to, so the
+// links between pages that templates generate work as they do on disk.  The
+// root path serves an index of all pages.  Markdown pages are served as
+// HTML with their links to other pages turned into anchors.
+func NewDocHandler(cmd *cobra.Command, opts *CobraManOptions, templateName string) http.Handler {
+	h := &docHandler{cmd: cmd, templateName: templateName}
+	if opts != nil {
+		h.opts = *opts
+	}
+	// Pages are always served uncompressed
+	h.opts.Gzip = false
+	return h
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{ .Title }}</title></head>
+<body>
+<h1>{{ .Title }}</h1>
+<ul>
+{{- range .Pages }}
+<li><a href="{{ .Href }}">{{ .CommandPath }}</a>{{ if .Short }} - {{ .Short }}{{ end }}</li>
+{{- end }}
+</ul>
+</body>
+</html>
+`))
+
+var markdownPageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{ .Title }}</title></head>
+<body>
+<pre>{{ .Content }}</pre>
+</body>
+</html>
+`))
+
+// markdownLink matches a markdown link to another page, which is relative
+var markdownLink = regexp.MustCompile(`\[([^\]]*)\]\(([^():\s]+\.md)\)`)
+
+type indexEntry struct {
+	Href        string
+	CommandPath string
+	Short       string
+}
+
+func (h *docHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !hasTemplate(h.templateName) {
+		http.Error(w, "the given template has not been registered: "+h.templateName, http.StatusInternalServerError)
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// Every request gets fresh options so the date and template are current
+	opts := h.opts
+	validate(&opts, h.templateName)
+	files, err := pageFiles(h.cmd, &opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if name == "" || name == "index.html" {
+		h.serveIndex(w, files)
+		return
+	}
+
+	for _, pf := range files {
+		if filepath.ToSlash(pf.name) != name {
+			continue
+		}
+		// An alias is served as the page it points to
+		pf.target = ""
+		buf := new(bytes.Buffer)
+		if err := generatePage(buf, pf, &opts, h.templateName); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if path.Ext(name) == ".md" {
+			h.serveMarkdown(w, pf, buf.String())
+			return
+		}
+		w.Header().Set("Content-Type", pageContentType(name))
+		w.Write(buf.Bytes())
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (h *docHandler) serveIndex(w http.ResponseWriter, files []pageFile) {
+	values := struct {
+		Title string
+		Pages []indexEntry
+	}{Title: h.cmd.CommandPath()}
+	for _, pf := range files {
+		if pf.target != "" {
+			continue
+		}
+		values.Pages = append(values.Pages, indexEntry{
+			Href:        filepath.ToSlash(pf.name),
+			CommandPath: pf.cmd.CommandPath(),
+			Short:       pf.cmd.Short,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, values); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveMarkdown serves the markdown page content as preformatted HTML in
+// which the links to other pages work.
+func (h *docHandler) serveMarkdown(w http.ResponseWriter, pf pageFile, content string) {
+	linked := markdownLink.ReplaceAllString(html.EscapeString(content), `<a href="$2">[$1]</a>`)
+	values := struct {
+		Title   string
+		Content template.HTML
+	}{Title: pf.cmd.CommandPath(), Content: template.HTML(linked)}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := markdownPageTemplate.Execute(w, values); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// pageContentType lets a browser show a page inline.  Anything that isn't
+// HTML, such as troff, is shown as plain text.
+func pageContentType(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm":
+		return "text/html; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// templateReloader registers a template file again whenever it changes
+// before passing requests on to the next handler.
+type templateReloader struct {
+	name, separator, extension, filename string
+
+	next    http.Handler
+	lock    sync.Mutex
+	modTime time.Time
+}
+
+func (tr *templateReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := tr.reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tr.next.ServeHTTP(w, r)
+}
+
+func (tr *templateReloader) reload() error {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	info, err := os.Stat(tr.filename)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(tr.modTime) {
+		return nil
+	}
+	if err := RegisterTemplateFile(tr.name, tr.separator, tr.extension, tr.filename); err != nil {
+		return err
+	}
+	tr.modTime = info.ModTime()
+	return nil
+}
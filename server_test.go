@@ -0,0 +1,110 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func TestDocHandler(t *testing.T) {
+	cmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	cmd2 := &cobra.Command{Use: "bar", Short: "bar things", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.AddCommand(cmd2)
+
+	handler := NewDocHandler(cmd, &CobraManOptions{Gzip: true}, "markdown")
+	rec := get(t, handler, "/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `<li><a href="foo.md">foo</a> - the foo app</li>`)
+	assert.Contains(t, rec.Body.String(), `<li><a href="foo_bar.md">foo bar</a> - bar things</li>`)
+
+	// The link in the See Also section resolves to the page of the parent
+	rec = get(t, handler, "/foo_bar.md")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<title>foo bar</title>")
+	assert.Contains(t, rec.Body.String(), "## foo bar\n")
+	assert.Contains(t, rec.Body.String(), `* <a href="foo.md">[foo]</a>`)
+	assert.Equal(t, http.StatusOK, get(t, handler, "/foo.md").Code)
+
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/foo_cat.md").Code)
+	assert.Equal(t, http.StatusInternalServerError, get(t, NewDocHandler(cmd, nil, "nope"), "/").Code)
+
+	handler = NewDocHandler(cmd, &CobraManOptions{SectionDirectories: true}, "troff")
+	assert.Contains(t, get(t, handler, "/").Body.String(), `<a href="man1/foo-bar.1">`)
+	assert.Contains(t, get(t, handler, "/man1/foo-bar.1").Body.String(), ".SH NAME\nfoo\\-bar - bar things")
+	assert.Equal(t, "text/plain; charset=utf-8", get(t, handler, "/man1/foo-bar.1").Header().Get("Content-Type"))
+}
+
+func TestDocHandlerConcurrent(t *testing.T) {
+	cmd := &cobra.Command{Use: "foo", Short: "the foo app"}
+	cmd.PersistentFlags().Bool("verbose", false, "be loud")
+	for _, name := range []string{"zap", "cat", "bar"} {
+		child := &cobra.Command{Use: name, Run: func(cmd *cobra.Command, args []string) {}}
+		child.Flags().String("name", "", "the name")
+		cmd.AddCommand(child)
+	}
+
+	handler := NewDocHandler(cmd, nil, "troff")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			page := []string{"/", "/foo.1", "/foo-bar.1", "/foo-cat.1", "/foo-zap.1"}[i%5]
+			assert.Equal(t, http.StatusOK, get(t, handler, page).Code, page)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTemplateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	templateFile := filepath.Join(dir, "page.tmpl")
+	assert.NoError(t, ioutil.WriteFile(templateFile, []byte("First {{ .CommandPath }}"), 0644))
+
+	cmd := &cobra.Command{Use: "foo"}
+	reloader := &templateReloader{name: templateFile, separator: "_", extension: "txt", filename: templateFile}
+	reloader.next = NewDocHandler(cmd, nil, templateFile)
+	assert.Equal(t, "First foo", get(t, reloader, "/foo.txt").Body.String())
+
+	assert.NoError(t, ioutil.WriteFile(templateFile, []byte("Second {{ .CommandPath }}"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(templateFile, later, later))
+	assert.Equal(t, "Second foo", get(t, reloader, "/foo.txt").Body.String())
+
+	// A broken template is reported rather than served
+	assert.NoError(t, ioutil.WriteFile(templateFile, []byte("Third {{ "), 0644))
+	later = later.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(templateFile, later, later))
+	assert.Equal(t, http.StatusInternalServerError, get(t, reloader, "/foo.txt").Code)
+}
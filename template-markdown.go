@@ -14,17 +14,42 @@
 package cobraman
 
 func init() {
-	RegisterTemplate("markdown", "_", "md", markdownTemplate)
+	RegisterTemplateWithDescription("markdown", "_", "md", "Markdown page", markdownTemplate)
 }
 
 // markdownTemplate is a template what will generate markdown syntax documentation.
-const markdownTemplate = `## {{.CommandPath}}
+const markdownTemplate = `## {{.CommandPath}}{{ if .Hidden }} (internal){{ end }}
 
 {{ .ShortDescription }}
+{{- if .AliasPaths }}
+
+Aliases: {{ range $index, $element := .AliasPaths }}{{ if $index }}, {{ end }}{{ $element }}{{ end }}
+{{- end }}
 
 ### Synopsis
+{{- if not (or .IsHelpTopic .SubCommands) }}
+
+` + "`" + `{{ .CommandPath }}{{ if .AllFlags }} [flags]{{ end }}{{ with .Args.Synopsis }} {{ . }}{{ end }}` + "`" + `
+{{- end }}
 
 {{ .Description }}
+{{- if .Args.ValidArgs }}
+
+Valid arguments: {{ range $index, $element := .Args.ValidArgs }}{{ if $index }}, {{ end }}` + "`" + `{{ $element }}` + "`" + `{{ end }}
+{{- if .Args.ArgAliases }}
+
+Also accepted: {{ range $index, $element := .Args.ArgAliases }}{{ if $index }}, {{ end }}` + "`" + `{{ $element }}` + "`" + `{{ end }}
+{{- end }}
+{{- end }}
+
+{{- if .Args.Declared }}
+
+### Arguments
+
+{{ range .Args.Positions -}}
+* ` + "`" + `<{{ .Name }}>{{ if .Repeated }}...{{ end }}` + "`" + `{{ if .Optional }} (optional){{ end }} - {{ .Description }}
+{{ end }}
+{{- end }}
 
 {{- if .AllFlags }}
 
@@ -34,11 +59,32 @@ The following options are supported:
 
 {{ range .AllFlags -}}
 * {{ if .Shorthand }}{{ print "-" .Shorthand }}, {{ end -}}{{ print "--" .Name }}
-{{- if not .NoOptDefVal }}{{if .ArgHint }}=<{{ .ArgHint }}>{{ else }}=<{{ .DefValue }}>{{ end }}{{ end }}
-{{- print " - " .Usage }}
+{{- if not .NoOptDefVal }}{{if .Placeholder }}=<{{ .Placeholder }}>{{ else }}=<{{ .DefValue }}>{{ end }}{{ end }}
+{{- print " - " }}{{ if .Hidden }}(internal) {{ end }}{{ .Usage }}{{ if .Default }} (default {{ .Default }}){{ end }}
 {{ end }}
 {{- end }}
 
+{{- if or .Deprecated .DeprecatedCommands .DeprecatedFlags }}
+
+### Deprecated
+{{- if .Deprecated }}
+
+This command is deprecated: {{ .Deprecated }}
+{{- end }}
+{{- if or .DeprecatedCommands .DeprecatedFlags }}
+{{ range .DeprecatedCommands }}
+* [{{ .CmdPath }}]({{ .CmdPath | underscoreify }}.md) - {{ .Deprecated }}
+{{- end }}
+{{- range .DeprecatedFlags }}
+{{- if .Deprecated }}
+* {{ if .Shorthand }}{{ print "-" .Shorthand }}, {{ end }}{{ print "--" .Name }} - {{ .Deprecated }}
+{{- else }}
+* {{ print "-" .Shorthand }} - Shorthand of {{ print "--" .Name }}: {{ .ShorthandDeprecated }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+
 {{- if .Environment }}
 
 ### Environment
@@ -76,7 +122,7 @@ Page auto-generated by rayjohnson/cobraman and spf13/cobra
 ### See Also
 
 {{- range $index, $element := .SeeAlsos}}
-* [{{ $element.CmdPath }}]({{ $element.CmdPath | underscoreify }}.md)
+* [{{ $element.CmdPath }}]({{ $element.CmdPath | underscoreify }}.md){{ if $element.Hidden }} (internal){{ end }}{{ if $element.Deprecated }} (deprecated){{ end }}
 {{- end }}
 {{- end }}
 
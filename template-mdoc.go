@@ -14,7 +14,7 @@
 package cobraman
 
 func init() {
-	RegisterTemplate("mdoc", "-", "use_section", mdocManTemplate)
+	RegisterTemplateWithDescription("mdoc", "-", "use_section", "Man page using the mdoc macro package", mdocManTemplate)
 }
 
 // mdocManTemplate is a template what will use the mdoc macro package.
@@ -30,27 +30,59 @@ const mdocManTemplate = `.\" Man page for {{.CommandPath}}
 ." This file auto-generated by github.com/rayjohnson/cobraman
 .Sh NAME
 .Nm {{ .CommandPath | dashify | backslashify }}
-{{- if .ShortDescription }}
-.Nd {{ .ShortDescription }}
+{{- range .AliasPaths }} ,
+.Nm {{ . | dashify | backslashify }}
 {{- end }}
+{{- if or .ShortDescription .Hidden }}
+.Nd {{ .ShortDescription }}{{ if .Hidden }}{{ if .ShortDescription }} {{ end }}(internal){{ end }}
+{{- end }}
+{{- if not .IsHelpTopic }}
 .Sh SYNOPSIS
 {{- if .SubCommands }}
 {{- range .SubCommands }}
-.Nm {{ . }} Op Fl flags Op args
+.Nm {{ .CmdPath }}{{ if .Hidden }} Li ( internal ){{ end }}{{ if .Deprecated }} Li ( deprecated ){{ end }} Op Fl flags Op args
 {{- end }}
 {{- else }}
-.Nm {{ .CommandPath }}
+.Nm {{ .CommandPath }}{{ range .Aliases }} | {{ . }}{{ end }}
 {{- range .AllFlags }}
 .Op Fl {{ if .Shorthand }}{{ .Shorthand | backslashify }} | {{ end -}}
-{{ print "-" .Name | backslashify }}
+{{ print "-" .Name | backslashify }}{{ if .Hidden }} Li ( internal ){{ end }}
+{{- end }}
+{{- $nested := false }}
+{{- range .Args.Positions }}
+{{- if not .Optional }}
+.{{ if .Choices }}Brq Cm {{ range $i, $c := .Choices }}{{ if $i }} | {{ end }}{{ $c | backslashify }}{{ end }}{{ else }}Ar {{ .Name | backslashify }}{{ end }}{{ if .Repeated }} ...{{ end }}
+{{- else }}{{ if $nested }} {{ else }}
+.{{ end -}}
+Op {{ if .Choices }}Cm {{ range $i, $c := .Choices }}{{ if $i }} | {{ end }}{{ $c | backslashify }}{{ end }}{{ else }}Ar {{ .Name | backslashify }}{{ end }}{{ if .Repeated }} ...{{ end }}
 {{- end }}
-{{ if not .NoArgs }}.Op Fl <args>
+{{- $nested = .Optional }}
 {{- end }}
 {{- end }}
 .Ek
+{{- end }}
 .Sh DESCRIPTION
 .Nm
 {{ .Description | simpleToMdoc }}
+{{- if .Args.ValidArgs }}
+.Pp
+Valid arguments are
+.Cm {{ range $index, $element := .Args.ValidArgs }}{{ if $index }} , {{ end }}{{ $element | backslashify }}{{ end }} .
+{{- if .Args.ArgAliases }}
+.Pp
+Also accepted are
+.Cm {{ range $index, $element := .Args.ArgAliases }}{{ if $index }} , {{ end }}{{ $element | backslashify }}{{ end }} .
+{{- end }}
+{{- end }}
+{{- if .Args.Declared }}
+.Sh ARGUMENTS
+.Bl -tag -width Ds
+{{- range .Args.Positions }}
+.It Ar {{ .Name | backslashify }}{{ if .Repeated }} ...{{ end }}{{ if .Optional }} (optional){{ end }}
+{{ .Description | backslashify }}
+{{- end }}
+.El
+{{- end }}
 {{- if .AllFlags }}
 .Pp
 The options are as follows:
@@ -60,11 +92,36 @@ The options are as follows:
 .Pp
 .It {{ if .Shorthand }}Fl {{ .Shorthand | backslashify }}, {{ end -}}
 Fl {{ print "-" .Name | backslashify }}
-{{- if not .NoOptDefVal }} Ar {{if .ArgHint }} {{ .ArgHint }}{{ else }} {{ .DefValue }}{{ end }}{{ end }}
-{{ .Usage | backslashify }}
+{{- if not .NoOptDefVal }} Ar {{if .Placeholder }} {{ .Placeholder | backslashify }}{{ else }} {{ .DefValue }}{{ end }}{{ end }}
+{{ if .Hidden }}(internal) {{ end }}{{ .Usage | backslashify }}{{ if .Default }} (default {{ .Default | backslashify }}){{ end }}
 {{ end }}
 .El
 {{- end }}
+{{- if or .Deprecated .DeprecatedCommands .DeprecatedFlags }}
+.Sh DEPRECATED
+{{- if .Deprecated }}
+This command is deprecated: {{ .Deprecated | backslashify }}
+{{- end }}
+{{- if or .DeprecatedCommands .DeprecatedFlags }}
+.Bl -tag -width Ds
+{{- range .DeprecatedCommands }}
+.It Cm {{ .CmdPath | backslashify }}
+{{ .Deprecated | backslashify }}
+{{- end }}
+{{- range .DeprecatedFlags }}
+{{- if .Deprecated }}
+.It {{ if .Shorthand }}Fl {{ .Shorthand | backslashify }}, {{ end }}Fl {{ print "-" .Name | backslashify }}
+{{ .Deprecated | backslashify }}
+{{- else }}
+.It Fl {{ .Shorthand | backslashify }}
+Shorthand of
+.Fl {{ print "-" .Name | backslashify }} :
+{{ .ShorthandDeprecated | backslashify }}
+{{- end }}
+{{- end }}
+.El
+{{- end }}
+{{- end }}
 {{- if .Environment }}
 .Sh ENVIRONMENT
 {{ .Environment | simpleToMdoc }}
@@ -91,7 +148,7 @@ Page auto-generated by rayjohnson/cobraman and spf13/cobra
 .Sh SEE ALSO
 {{- range $index, $element := .SeeAlsos}}
 {{- if $index}} ,{{end}}
-.Xr {{$element.CmdPath}} {{$element.Section}}
+.Xr {{$element.CmdPath}} {{$element.Section}}{{ if $element.Hidden }} ( internal ){{ end }}{{ if $element.Deprecated }} ( deprecated ){{ end }}
 {{- end }}
 {{- end }}
 ." This file auto-generated by github.com/rayjohnson/cobraman
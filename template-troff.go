@@ -14,7 +14,7 @@
 package cobraman
 
 func init() {
-	RegisterTemplate("troff", "-", "use_section", troffManTemplate)
+	RegisterTemplateWithDescription("troff", "-", "use_section", "Man page using basic troff macros", troffManTemplate)
 }
 
 // troffManTemplate generates a man page with only basic troff macros
@@ -26,34 +26,76 @@ const troffManTemplate = `.TH "{{.CommandPath | dashify | backslashify | upper}}
 ." This file auto-generated by github.com/rayjohnson/cobraman
 .SH NAME
 {{ .CommandPath | dashify | backslashify }}
+{{- range .AliasPaths }}, {{ . | dashify | backslashify }}{{ end }}
 {{- if .ShortDescription }} - {{ .ShortDescription }}
  {{- end }}
+{{- if .Hidden }} (internal){{ end }}
+{{- if not .IsHelpTopic }}
 .SH SYNOPSIS
 .sp
 {{- if .SubCommands }}
 {{- range .SubCommands }}
-\fB{{ . }}\fR [ flags ]
+\fB{{ .CmdPath }}\fR{{ if .Hidden }} (internal){{ end }}{{ if .Deprecated }} (deprecated){{ end }} [ flags ]
 .br{{ end }}
 {{- else }}
-\fB{{ .CommandPath }} \fR
+\fB{{ .CommandPath }}{{ range .Aliases }}|{{ . }}{{ end }} \fR
 {{- range .AllFlags -}}
 [{{ if .Shorthand }}\fI{{ print "-" .Shorthand | backslashify }}\fP|{{ end -}}
-\fI{{ print "--" .Name | backslashify }}\fP] {{ end }}
-{{- if not .NoArgs }}[<args>]{{ end }}
+\fI{{ print "--" .Name | backslashify }}\fP{{ if .Hidden }}\ (internal){{ end }}] {{ end }}
+{{- .Args.Synopsis | backslashify }}
+{{- end }}
 {{- end }}
 .SH DESCRIPTION
 .PP
 {{ .Description | simpleToTroff }}
+{{- if .Args.ValidArgs }}
+.PP
+Valid arguments: {{ range $index, $element := .Args.ValidArgs }}{{ if $index }}, {{ end }}\fB{{ $element | backslashify }}\fP{{ end }}
+{{- if .Args.ArgAliases }}
+.PP
+Also accepted: {{ range $index, $element := .Args.ArgAliases }}{{ if $index }}, {{ end }}\fB{{ $element | backslashify }}\fP{{ end }}
+{{- end }}
+{{- end }}
+{{- if .Args.Declared }}
+.SH ARGUMENTS
+{{- range .Args.Positions }}
+.TP
+\fI{{ .Name | backslashify }}\fP{{ if .Repeated }} ...{{ end }}{{ if .Optional }} (optional){{ end }}
+{{ .Description | backslashify }}
+{{- end }}
+{{- end }}
 {{- if .AllFlags }}
 .SH OPTIONS
 {{ range .AllFlags -}}
 .TP
 {{ if .Shorthand }}\fB{{ print "-" .Shorthand | backslashify }}\fP, {{ end -}}
 \fB{{ print "--" .Name | backslashify }}\fP{{ if not .NoOptDefVal }} =
-{{- if .ArgHint }} <{{ .ArgHint }}>{{ else }} {{ .DefValue }}{{ end }}{{ end }}
-{{ .Usage | backslashify }}
+{{- if .Placeholder }} <{{ .Placeholder | backslashify }}>{{ else }} {{ .DefValue }}{{ end }}{{ end }}
+{{ if .Hidden }}(internal) {{ end }}{{ .Usage | backslashify }}{{ if .Default }} (default {{ .Default | backslashify }}){{ end }}
 {{ end }}
 {{- end -}}
+{{- if or .Deprecated .DeprecatedCommands .DeprecatedFlags }}
+.SH DEPRECATED
+{{- if .Deprecated }}
+.PP
+This command is deprecated: {{ .Deprecated | backslashify }}
+{{- end }}
+{{- range .DeprecatedCommands }}
+.TP
+\fB{{ .CmdPath | backslashify }}\fP
+{{ .Deprecated | backslashify }}
+{{- end }}
+{{- range .DeprecatedFlags }}
+.TP
+{{- if .Deprecated }}
+{{ if .Shorthand }}\fB{{ print "-" .Shorthand | backslashify }}\fP, {{ end }}\fB{{ print "--" .Name | backslashify }}\fP
+{{ .Deprecated | backslashify }}
+{{- else }}
+\fB{{ print "-" .Shorthand | backslashify }}\fP
+Shorthand of \fB{{ print "--" .Name | backslashify }}\fP: {{ .ShorthandDeprecated | backslashify }}
+{{- end }}
+{{- end }}
+{{- end }}
 {{- if .Environment }}
 .SH ENVIRONMENT
 .PP
@@ -83,7 +125,7 @@ const troffManTemplate = `.TH "{{.CommandPath | dashify | backslashify | upper}}
 {{- if .SeeAlsos }}
 .SH SEE ALSO
 {{- range .SeeAlsos }}
-.BR {{ .CmdPath | dashify | backslashify }} ({{ .Section }})
+.BR {{ .CmdPath | dashify | backslashify }} ({{ .Section }}){{ if .Hidden }}\ (internal){{ end }}{{ if .Deprecated }}\ (deprecated){{ end }}
 {{- end }}
 {{- end }}
 ." This file auto-generated by github.com/rayjohnson/cobraman
@@ -14,18 +14,26 @@
 package cobraman
 
 import (
+	"io/ioutil"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 )
 
 type manTemplate struct {
-	separator string
-	extension string
-	template  *template.Template
+	separator   string
+	extension   string
+	description string
+	template    *template.Template
 }
 
 var templateMap = make(map[string]manTemplate)
 
+// templateLock guards templateMap as templates can be registered again while
+// pages are being served
+var templateLock sync.RWMutex
+
 var templateFuncs = template.FuncMap{
 	"upper":          strings.ToUpper,
 	"backslashify":   backslashify,
@@ -55,18 +63,85 @@ func AddTemplateFuncs(tmplFuncs template.FuncMap) {
 // also takes a separator and file extension to be used when generating the file names for
 // the generated files.
 func RegisterTemplate(name string, separator string, extension string, templateString string) {
+	if err := registerTemplate(name, separator, extension, templateString); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterTemplateWithDescription works like RegisterTemplate but also takes a
+// short description of the template that is shown when listing the templates.
+func RegisterTemplateWithDescription(name string, separator string, extension string, description string, templateString string) {
+	RegisterTemplate(name, separator, extension, templateString)
+
+	templateLock.Lock()
+	defer templateLock.Unlock()
+	t := templateMap[name]
+	t.description = description
+	templateMap[name] = t
+}
+
+// RegisterTemplateFile works like RegisterTemplate but reads the template from
+// a file.  It returns an error rather than panicking if the file can not be read
+// or parsed, so it can be used to try out templates at runtime.
+func RegisterTemplateFile(name string, separator string, extension string, filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return registerTemplate(name, separator, extension, string(content))
+}
+
+func registerTemplate(name string, separator string, extension string, templateString string) error {
 	// Build the template
-	parsedTemplate := template.Must(template.New(name).Funcs(templateFuncs).Parse(templateString))
+	parsedTemplate, err := template.New(name).Funcs(templateFuncs).Parse(templateString)
+	if err != nil {
+		return err
+	}
 
 	t := manTemplate{
 		separator: separator,
 		extension: extension,
 		template:  parsedTemplate,
 	}
+	templateLock.Lock()
+	defer templateLock.Unlock()
 	templateMap[name] = t
+	return nil
+}
+
+// TemplateInfo describes a registered template.
+type TemplateInfo struct {
+	Name        string
+	Separator   string
+	Extension   string // may be the special string "use_section"
+	Description string
+}
+
+// Templates returns information about all registered templates sorted by name.
+func Templates() []TemplateInfo {
+	templateLock.RLock()
+	defer templateLock.RUnlock()
+	infos := make([]TemplateInfo, 0, len(templateMap))
+	for name, t := range templateMap {
+		infos = append(infos, TemplateInfo{
+			Name:        name,
+			Separator:   t.separator,
+			Extension:   t.extension,
+			Description: t.description,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
 }
 
 func getTemplate(name string) (string, string, *template.Template) {
+	templateLock.RLock()
+	defer templateLock.RUnlock()
 	t := templateMap[name]
 	return t.separator, t.extension, t.template
 }
+
+func hasTemplate(name string) bool {
+	_, _, t := getTemplate(name)
+	return t != nil
+}
@@ -15,6 +15,8 @@ package cobraman
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 	"text/template"
@@ -28,6 +30,45 @@ func TestRegisterTemplate(t *testing.T) {
 	assert.NotPanics(t, func() { RegisterTemplate("good", "-", "txt", "Hello {{ \"world\" }} ") }, "The code should not panic")
 }
 
+func TestRegisterTemplateFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "cobraman")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("File says {{ .CommandPath | upper }}")
+	f.Close()
+
+	assert.NoError(t, RegisterTemplateFile("from-file", "-", "txt", f.Name()))
+	cmd := &cobra.Command{Use: "foo"}
+	opts := CobraManOptions{}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, GenerateOnePage(cmd, &opts, "from-file", buf))
+	assert.Equal(t, "File says FOO", buf.String())
+
+	assert.Error(t, RegisterTemplateFile("from-file", "-", "txt", f.Name()+".missing"))
+	ioutil.WriteFile(f.Name(), []byte("what {{ "), 0644)
+	assert.Error(t, RegisterTemplateFile("from-file", "-", "txt", f.Name()))
+}
+
+func TestTemplates(t *testing.T) {
+	RegisterTemplateWithDescription("described", ".", "txt", "A test template", "Hello")
+	var found bool
+	infos := Templates()
+	for i, info := range infos {
+		if i > 0 {
+			assert.True(t, infos[i-1].Name < info.Name, "templates should be sorted by name")
+		}
+		switch info.Name {
+		case "described":
+			assert.Equal(t, TemplateInfo{Name: "described", Separator: ".", Extension: "txt", Description: "A test template"}, info)
+			found = true
+		case "troff":
+			assert.Equal(t, "use_section", info.Extension)
+			assert.NotEmpty(t, info.Description)
+		}
+	}
+	assert.True(t, found)
+}
+
 func TestCustomerTemplate(t *testing.T) {
 	buf := new(bytes.Buffer)
 
@@ -0,0 +1,145 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const defaultTerminalWidth = 80
+
+// PreviewPage formats the page for cmd like RenderTerminalPage and shows it
+// on w.  If w is a terminal the page is wrapped to its width and shown with
+// $MANPAGER or $PAGER (less if neither is set).  Otherwise it is written as
+// plain text that is wrapped to $COLUMNS or 80 columns.
+func PreviewPage(cmd *cobra.Command, opts *CobraManOptions, templateName string, w io.Writer) error {
+	return previewPage(cmd, opts, templateName, w, 0, true)
+}
+
+// previewPage does the work of PreviewPage.  A width of 0 means to use the
+// width of the terminal.
+func previewPage(cmd *cobra.Command, opts *CobraManOptions, templateName string, w io.Writer, width int, usePager bool) error {
+	tty := isTerminal(w)
+	if width <= 0 {
+		width = terminalWidth(w)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := RenderTerminalPage(cmd, opts, templateName, width, buf); err != nil {
+		return err
+	}
+	if !tty {
+		_, err := w.Write(stripOverstrike(buf.Bytes()))
+		return err
+	}
+	if usePager {
+		return runPager(buf, w)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func terminalWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+// runPager shows content with the user's pager.  If no pager can be found
+// the content is written straight to w.
+func runPager(content io.Reader, w io.Writer) error {
+	pager := os.Getenv("MANPAGER")
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager == "" {
+		pager = "less"
+	}
+
+	args := strings.Fields(pager)
+	if len(args) == 0 {
+		_, err := io.Copy(w, content)
+		return err
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		_, err := io.Copy(w, content)
+		return err
+	}
+
+	pagerCmd := exec.Command(path, args[1:]...)
+	pagerCmd.Stdin = content
+	pagerCmd.Stdout = w
+	pagerCmd.Stderr = os.Stderr
+	return pagerCmd.Run()
+}
+
+// AddManCommand adds a man subcommand to root, the root command of your
+// application, so users can read the man page of any command without having
+// the pages installed, e.g. "app man remote add".  The page is generated with
+// the passed in CobraManOptions and the troff template and shown like
+// PreviewPage does.  The new command is returned so it can be adjusted.
+func AddManCommand(root *cobra.Command, opts *CobraManOptions) *cobra.Command {
+	if opts == nil {
+		opts = &CobraManOptions{}
+	}
+
+	manCmd := &cobra.Command{
+		Use:   "man [command path]",
+		Short: "Show the manual page of a command",
+		RunE: func(myCmd *cobra.Command, args []string) error {
+			cmd, err := findCommand(root, args)
+			if err != nil {
+				return err
+			}
+			runOpts := *opts
+			return PreviewPage(cmd, &runOpts, "troff", myCmd.OutOrStdout())
+		},
+		ValidArgsFunction: func(myCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			cmd, err := findCommand(root, args)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			var names []string
+			for _, c := range cmd.Commands() {
+				if c.IsAvailableCommand() && strings.HasPrefix(c.Name(), toComplete) {
+					names = append(names, c.Name())
+				}
+			}
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	root.AddCommand(manCmd)
+
+	return manCmd
+}
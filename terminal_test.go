@@ -0,0 +1,54 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddManCommand(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	remoteCmd := &cobra.Command{Use: "remote", Short: "Remote things"}
+	addCmd := &cobra.Command{Use: "add", Short: "Add a remote", Run: func(cmd *cobra.Command, args []string) {}}
+	remoteCmd.AddCommand(addCmd)
+	appCmd.AddCommand(remoteCmd)
+	manCmd := AddManCommand(appCmd, &CobraManOptions{CenterHeader: "Foo Manual"})
+	assert.Equal(t, appCmd, manCmd.Parent())
+
+	buf := new(bytes.Buffer)
+	appCmd.SetOutput(buf)
+	appCmd.SetArgs([]string{"man", "remote", "add"})
+	assert.NoError(t, appCmd.Execute())
+	assert.Regexp(t, "^FOO-REMOTE-ADD\\(1\\) +Foo Manual +FOO-REMOTE-ADD\\(1\\)\n", buf.String())
+	assert.Contains(t, buf.String(), "NAME\n       foo-remote-add - Add a remote\n")
+
+	buf.Reset()
+	appCmd.SetArgs([]string{"man"})
+	assert.NoError(t, appCmd.Execute())
+	assert.Regexp(t, "^FOO\\(1\\) ", buf.String())
+
+	appCmd.SetArgs([]string{"man", "remote", "nope"})
+	assert.Error(t, appCmd.Execute())
+
+	names, _ := manCmd.ValidArgsFunction(manCmd, []string{"remote"}, "a")
+	assert.Equal(t, []string{"add"}, names)
+	// Newer cobra versions add their own completion command to the tree
+	names, _ = manCmd.ValidArgsFunction(manCmd, nil, "")
+	assert.Contains(t, names, "man")
+	assert.Contains(t, names, "remote")
+}
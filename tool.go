@@ -14,9 +14,15 @@
 package cobraman
 
 import (
+	"fmt"
+	"net/http"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // DocGenTool is an opaque type created by CreateDocGenCmdLineTool
@@ -63,32 +69,436 @@ func (dg *DocGenTool) AddBashCompletionGenerator(fileName string) *DocGenTool {
 	return dg
 }
 
+// AddTemplateLister will create a list-templates subcommand for the utility
+// tool that shows the name, file name separator, extension and description of
+// every registered template.
+func (dg *DocGenTool) AddTemplateLister() *DocGenTool {
+	listCmd := &cobra.Command{
+		Use:   "list-templates",
+		Args:  cobra.NoArgs,
+		Short: "List the registered templates",
+		RunE: func(myCmd *cobra.Command, args []string) error {
+			w := tabwriter.NewWriter(myCmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tSEPARATOR\tEXTENSION\tDESCRIPTION")
+			for _, info := range Templates() {
+				fmt.Fprintf(w, "%s\t%q\t%s\t%s\n", info.Name, info.Separator, info.Extension, info.Description)
+			}
+			return w.Flush()
+		},
+	}
+
+	dg.docCmd.AddCommand(listCmd)
+
+	return dg
+}
+
 // AddDocGenerator will create a subcommand for the utility tool that will
 // generate documentation with the passed in CobraManOptions and templateName.
-// It supports a --directory flag for where to place the generated files.  The
-// subcommand will be named generate-<templateName> where templateName is the
-// same as the template used to generate the documentation.
+// It supports a --directory flag for where to place the generated files, a
+// --check flag that fails if those files are out of date, a --dry-run flag
+// that lists the files instead of writing them, a --command flag to write
+// the page of a single command to stdout and an --archive flag to write the
+// files into a .tar.gz or .zip file instead of --directory.  Only one of
+// --check, --dry-run, --command and --archive can be given.  The fields of
+// CobraManOptions can be overridden with flags such as --date and
+// --left-footer.  The subcommand will be named generate-<templateName> where
+// templateName is the same as the template used to generate the
+// documentation.
 func (dg *DocGenTool) AddDocGenerator(opts *CobraManOptions, templateName string) *DocGenTool {
 	// Make sure template exists or we will later get runtime panic
-	_, ok := templateMap[templateName]
-	if !ok {
+	if !hasTemplate(templateName) {
 		panic("the given template has not been registered: " + templateName)
 	}
 
+	var check, dryRun bool
+	var command, archive, templateFile, templateSeparator, templateExtension string
+	var optFlags *optionFlags
 	genCmd := &cobra.Command{
 		Use:   "generate-" + templateName,
 		Args:  cobra.NoArgs,
 		Short: "Generate docs with the " + templateName + " template",
 		RunE: func(myCmd *cobra.Command, args []string) error {
-			return GenerateDocs(dg.appCmd, opts, dg.installDirectory, templateName)
+			// The modes would otherwise quietly take priority over each
+			// other, so a --check could pass without checking anything
+			var modes []string
+			if command != "" {
+				modes = append(modes, "--command")
+			}
+			if check {
+				modes = append(modes, "--check")
+			}
+			if dryRun {
+				modes = append(modes, "--dry-run")
+			}
+			if archive != "" {
+				modes = append(modes, "--archive")
+			}
+			if len(modes) > 1 {
+				return fmt.Errorf("%s can't be used together", strings.Join(modes, " and "))
+			}
+
+			// Flags given on the command line override the options set in Go
+			runOpts := *opts
+			if err := optFlags.apply(&runOpts); err != nil {
+				return err
+			}
+
+			// A template file is registered under its own path and used
+			// in place of the template this subcommand was created for
+			tmplName := templateName
+			if templateFile != "" {
+				sep, ext, _ := getTemplate(templateName)
+				if myCmd.Flags().Changed("template-separator") {
+					sep = templateSeparator
+				}
+				if myCmd.Flags().Changed("template-extension") {
+					ext = templateExtension
+				}
+				if err := RegisterTemplateFile(templateFile, sep, ext, templateFile); err != nil {
+					return err
+				}
+				tmplName = templateFile
+			}
+
+			if command != "" {
+				return dg.renderPage(myCmd, &runOpts, tmplName, command)
+			}
+			if check {
+				return dg.checkDocs(myCmd, &runOpts, tmplName)
+			}
+			if dryRun {
+				return dg.listDocs(myCmd, &runOpts, tmplName)
+			}
+			if archive != "" {
+				return generateArchiveFile(dg.appCmd, &runOpts, archive, tmplName)
+			}
+			return GenerateDocs(dg.appCmd, &runOpts, dg.installDirectory, tmplName)
 		},
 	}
+	genCmd.Flags().BoolVar(&check, "check", false, "Report docs in --directory that are out of date without writing anything")
+	genCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the files that would be generated without writing anything")
+	genCmd.Flags().BoolVar(&dryRun, "list", false, "Same as --dry-run")
+	genCmd.Flags().StringVar(&command, "command", "", "Write the page for one command (e.g. \"app remote add\") to stdout")
+	genCmd.Flags().StringVar(&archive, "archive", "", "Write the docs into this .tar.gz, .tgz or .zip file instead of --directory")
+	genCmd.Flags().StringVar(&templateFile, "template-file", "", "Render with the template in this file instead of "+templateName)
+	genCmd.Flags().StringVar(&templateSeparator, "template-separator", "", "File name separator for --template-file (defaults to that of "+templateName+")")
+	genCmd.Flags().StringVar(&templateExtension, "template-extension", "", "File extension for --template-file, or use_section (defaults to that of "+templateName+")")
+	optFlags = addOptionFlags(genCmd.Flags())
 
 	dg.docCmd.AddCommand(genCmd)
 
 	return dg
 }
 
+// optionFlags are the command line flags that override CobraManOptions.
+type optionFlags struct {
+	flags      *pflag.FlagSet
+	opts       CobraManOptions
+	date       string
+	aliasPages string
+}
+
+func addOptionFlags(flags *pflag.FlagSet) *optionFlags {
+	of := &optionFlags{flags: flags}
+	flags.StringVar(&of.opts.Section, "section", "", "Man section of the pages")
+	flags.StringVar(&of.date, "date", "", "Date of the pages as 2006-01-02 or RFC 3339 (defaults to now)")
+	flags.StringVar(&of.opts.CenterFooter, "center-footer", "", "Center footer of the pages (defaults to the month and year of --date)")
+	flags.StringVar(&of.opts.LeftFooter, "left-footer", "", "Left footer of the pages, usually the name and version of the app")
+	flags.StringVar(&of.opts.CenterHeader, "center-header", "", "Center header of the pages")
+	flags.StringVar(&of.opts.Author, "author", "", "Content of the AUTHOR section")
+	flags.StringVar(&of.opts.Bugs, "bugs", "", "Content of the BUGS section")
+	flags.StringVar(&of.opts.Environment, "environment", "", "Content of the ENVIRONMENT section")
+	flags.StringVar(&of.opts.Files, "files", "", "Content of the FILES section")
+	flags.BoolVar(&of.opts.Gzip, "gzip", false, "Compress the generated files with gzip")
+	flags.BoolVar(&of.opts.SectionDirectories, "section-dirs", false, "Place pages in man<section> sub directories so --directory can be used in MANPATH")
+	flags.StringVar(&of.opts.ManifestFile, "manifest", "", "Record the generated files in this file in --directory")
+	flags.BoolVar(&of.opts.Prune, "prune", false, "Delete files listed in the previous --manifest that are no longer generated")
+	flags.IntVar(&of.opts.Parallelism, "parallel", 0, "Generate up to this many pages at the same time")
+	flags.BoolVar(&of.opts.IncludeHidden, "include-hidden", false, "Generate pages for hidden commands too, marked as internal")
+	flags.BoolVar(&of.opts.IncludeHiddenFlags, "include-hidden-flags", false, "List hidden flags too, marked as internal")
+	flags.BoolVar(&of.opts.IncludeDeprecated, "include-deprecated", false, "Generate pages for deprecated commands too and list deprecated commands and flags")
+	flags.BoolVar(&of.opts.ExcludeHelpTopics, "exclude-help-topics", false, "Leave out the pages of additional help topic commands")
+	flags.StringVar(&of.opts.HelpTopicSection, "help-topic-section", "", "Man section of the help topic pages (defaults to 7)")
+	flags.StringVar(&of.aliasPages, "alias-pages", "", "Add pages for command aliases: so for .so redirects or symlink for symbolic links")
+	flags.StringArrayVar(&of.opts.Include, "include", nil, "Only generate pages for commands whose path matches this pattern (e.g. \"app remote *\"), can be repeated")
+	flags.StringArrayVar(&of.opts.Exclude, "exclude", nil, "Leave out commands whose path matches this pattern and their children, can be repeated")
+	return of
+}
+
+// apply sets the options whose flags were given on the command line.
+func (of *optionFlags) apply(opts *CobraManOptions) error {
+	var err error
+	of.flags.Visit(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "section":
+			opts.Section = of.opts.Section
+		case "date":
+			var date time.Time
+			if date, err = parseDate(of.date); err == nil {
+				opts.Date = &date
+			}
+		case "center-footer":
+			opts.CenterFooter = of.opts.CenterFooter
+		case "left-footer":
+			opts.LeftFooter = of.opts.LeftFooter
+		case "center-header":
+			opts.CenterHeader = of.opts.CenterHeader
+		case "author":
+			opts.Author = of.opts.Author
+		case "bugs":
+			opts.Bugs = of.opts.Bugs
+		case "environment":
+			opts.Environment = of.opts.Environment
+		case "files":
+			opts.Files = of.opts.Files
+		case "gzip":
+			opts.Gzip = of.opts.Gzip
+		case "section-dirs":
+			opts.SectionDirectories = of.opts.SectionDirectories
+		case "manifest":
+			opts.ManifestFile = of.opts.ManifestFile
+		case "prune":
+			opts.Prune = of.opts.Prune
+		case "parallel":
+			opts.Parallelism = of.opts.Parallelism
+		case "include-hidden":
+			opts.IncludeHidden = of.opts.IncludeHidden
+		case "include-hidden-flags":
+			opts.IncludeHiddenFlags = of.opts.IncludeHiddenFlags
+		case "include-deprecated":
+			opts.IncludeDeprecated = of.opts.IncludeDeprecated
+		case "exclude-help-topics":
+			opts.ExcludeHelpTopics = of.opts.ExcludeHelpTopics
+		case "help-topic-section":
+			opts.HelpTopicSection = of.opts.HelpTopicSection
+		case "alias-pages":
+			opts.AliasPages = AliasPageMode(of.aliasPages)
+		case "include":
+			opts.Include = of.opts.Include
+		case "exclude":
+			opts.Exclude = of.opts.Exclude
+		}
+	})
+	return err
+}
+
+// checkDocs reports the differences found by CheckDocs and returns an
+// error if the docs in the install directory are not current.
+func (dg *DocGenTool) checkDocs(myCmd *cobra.Command, opts *CobraManOptions, templateName string) error {
+	result, err := CheckDocs(dg.appCmd, opts, dg.installDirectory, templateName)
+	if err != nil {
+		return err
+	}
+	if result.IsCurrent() {
+		return nil
+	}
+
+	out := myCmd.OutOrStdout()
+	for _, name := range result.Stale {
+		fmt.Fprintln(out, "stale:", name)
+	}
+	for _, name := range result.Missing {
+		fmt.Fprintln(out, "missing:", name)
+	}
+	for _, name := range result.Extra {
+		fmt.Fprintln(out, "extra:", name)
+	}
+
+	// The report above says it all so skip the usage message
+	myCmd.SilenceUsage = true
+	return fmt.Errorf("generated docs in %s are out of date", dg.installDirectory)
+}
+
+// AddPreviewer will create a preview subcommand for the utility tool that
+// shows the man page of a command of the companion app in the terminal,
+// e.g. "preview app remote add".  The page is generated with the passed in
+// CobraManOptions and the template given by the --template flag ("troff" by
+// default) and formatted without needing groff or man to be installed.
+func (dg *DocGenTool) AddPreviewer(opts *CobraManOptions) *DocGenTool {
+	if opts == nil {
+		opts = &CobraManOptions{}
+	}
+
+	var templateName string
+	var width int
+	var noPager bool
+	previewCmd := &cobra.Command{
+		Use:   "preview [command path]",
+		Short: "Show the man page of a command in the terminal",
+		RunE: func(myCmd *cobra.Command, args []string) error {
+			if !hasTemplate(templateName) {
+				return fmt.Errorf("the given template has not been registered: %s", templateName)
+			}
+			cmd, err := findCommand(dg.appCmd, args)
+			if err != nil {
+				return err
+			}
+			runOpts := *opts
+			return previewPage(cmd, &runOpts, templateName, myCmd.OutOrStdout(), width, !noPager)
+		},
+	}
+	previewCmd.Flags().StringVar(&templateName, "template", "troff", "Man template to format, such as troff or mdoc")
+	previewCmd.Flags().IntVar(&width, "width", 0, "Width to wrap the page to (defaults to the terminal width)")
+	previewCmd.Flags().BoolVar(&noPager, "no-pager", false, "Write the page without using a pager")
+
+	dg.docCmd.AddCommand(previewCmd)
+
+	return dg
+}
+
+// AddDocServer will create a serve subcommand for the utility tool that
+// starts a web server on localhost for looking through the docs of the
+// companion app.  Pages are generated with the passed in CobraManOptions and
+// the template given by the --template flag whenever they are requested, so
+// changes show up on reload.  A template file given with --template-file is
+// registered again each time it changes.
+func (dg *DocGenTool) AddDocServer(opts *CobraManOptions) *DocGenTool {
+	if opts == nil {
+		opts = &CobraManOptions{}
+	}
+
+	var addr, templateName, templateFile, templateSeparator, templateExtension string
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Args:  cobra.NoArgs,
+		Short: "Serve the docs on a local web server",
+		RunE: func(myCmd *cobra.Command, args []string) error {
+			if !hasTemplate(templateName) {
+				return fmt.Errorf("the given template has not been registered: %s", templateName)
+			}
+
+			handler := NewDocHandler(dg.appCmd, opts, templateName)
+			if templateFile != "" {
+				sep, ext, _ := getTemplate(templateName)
+				if myCmd.Flags().Changed("template-separator") {
+					sep = templateSeparator
+				}
+				if myCmd.Flags().Changed("template-extension") {
+					ext = templateExtension
+				}
+				reloader := &templateReloader{name: templateFile, separator: sep, extension: ext, filename: templateFile}
+				if err := reloader.reload(); err != nil {
+					return err
+				}
+				reloader.next = NewDocHandler(dg.appCmd, opts, templateFile)
+				handler = reloader
+			}
+
+			fmt.Fprintf(myCmd.OutOrStdout(), "Serving docs at http://%s/\n", addr)
+			return http.ListenAndServe(addr, handler)
+		},
+	}
+	serveCmd.Flags().StringVar(&addr, "addr", "localhost:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&templateName, "template", "markdown", "Template to generate the pages with")
+	serveCmd.Flags().StringVar(&templateFile, "template-file", "", "Generate the pages with the template in this file instead of --template")
+	serveCmd.Flags().StringVar(&templateSeparator, "template-separator", "", "File name separator for --template-file (defaults to that of --template)")
+	serveCmd.Flags().StringVar(&templateExtension, "template-extension", "", "File extension for --template-file (defaults to that of --template)")
+
+	dg.docCmd.AddCommand(serveCmd)
+
+	return dg
+}
+
+// AddConfigGenerator will create a generate subcommand for the utility tool
+// that reads a YAML, TOML or JSON file given with the --config flag (see
+// DocGenConfig).  The file fills in the passed in CobraManOptions, chooses the
+// templates to generate docs with and can override the annotation sections of
+// individual commands.  This lets the content of the pages be edited without
+// touching Go code.
+func (dg *DocGenTool) AddConfigGenerator(opts *CobraManOptions) *DocGenTool {
+	if opts == nil {
+		opts = &CobraManOptions{}
+	}
+
+	var configFile string
+	var optFlags *optionFlags
+	configCmd := &cobra.Command{
+		Use:   "generate",
+		Args:  cobra.NoArgs,
+		Short: "Generate docs as described by a config file",
+		RunE: func(myCmd *cobra.Command, args []string) error {
+			config, err := LoadConfig(configFile)
+			if err != nil {
+				return err
+			}
+			if len(config.Generators) == 0 {
+				return fmt.Errorf("%s: no generators are listed", configFile)
+			}
+			if err := config.ApplyCommands(dg.appCmd); err != nil {
+				return err
+			}
+
+			for _, gen := range config.Generators {
+				runOpts := *opts
+				if err := config.Options.Apply(&runOpts); err != nil {
+					return err
+				}
+				if err := optFlags.apply(&runOpts); err != nil {
+					return err
+				}
+				if gen.Archive != "" {
+					if err := generateArchiveFile(dg.appCmd, &runOpts, gen.Archive, gen.Template); err != nil {
+						return err
+					}
+					continue
+				}
+				directory := gen.Directory
+				if directory == "" {
+					directory = dg.installDirectory
+				}
+				if err := GenerateDocs(dg.appCmd, &runOpts, directory, gen.Template); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	configCmd.Flags().StringVar(&configFile, "config", "cobraman.yaml", "YAML, TOML or JSON file describing the docs to generate")
+	optFlags = addOptionFlags(configCmd.Flags())
+
+	dg.docCmd.AddCommand(configCmd)
+
+	return dg
+}
+
+// renderPage writes the page for the command found at commandPath to stdout.
+func (dg *DocGenTool) renderPage(myCmd *cobra.Command, opts *CobraManOptions, templateName string, commandPath string) error {
+	cmd, err := findCommand(dg.appCmd, strings.Fields(commandPath))
+	if err != nil {
+		return err
+	}
+	return GenerateOnePage(cmd, opts, templateName, myCmd.OutOrStdout())
+}
+
+// findCommand uses cobra's Find to look up the command named by args.  The
+// name of the root command itself may be included or left off.
+func findCommand(root *cobra.Command, args []string) (*cobra.Command, error) {
+	if len(args) > 0 && args[0] == root.Name() {
+		args = args[1:]
+	}
+	cmd, remaining, err := root.Find(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(remaining) > 0 {
+		return nil, fmt.Errorf("unknown command %q for %q", remaining[0], cmd.CommandPath())
+	}
+	return cmd, nil
+}
+
+// listDocs prints the files GenerateDocs would write and the command
+// each one documents.
+func (dg *DocGenTool) listDocs(myCmd *cobra.Command, opts *CobraManOptions, templateName string) error {
+	docs, err := ListDocs(dg.appCmd, opts, dg.installDirectory, templateName)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		fmt.Fprintf(myCmd.OutOrStdout(), "%s\t%s\n", doc.Path, doc.CommandPath)
+	}
+	return nil
+}
+
 // Execute will parse args and execute the command line
 func (dg *DocGenTool) Execute() error {
 	return dg.docCmd.Execute()
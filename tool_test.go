@@ -15,9 +15,14 @@ package cobraman
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,3 +88,232 @@ func TestExecute(t *testing.T) {
 	assert.NoError(t, dg.Execute())
 	checkForFile(t, "foo.txt")
 }
+
+func TestCheckFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	appCmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "child1", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(cmd2)
+
+	dg := CreateDocGenCmdLineTool(appCmd)
+	date, _ := time.Parse(time.RFC3339, "1968-06-21T15:04:05Z")
+	dg.AddDocGenerator(&CobraManOptions{Date: &date}, "markdown")
+	buf := new(bytes.Buffer)
+	dg.docCmd.SetOutput(buf)
+
+	dg.docCmd.SetArgs([]string{"generate-markdown", "--check", "--directory", dir})
+	assert.Error(t, dg.Execute())
+	assert.Contains(t, buf.String(), "missing: "+filepath.Join(dir, "foo_child1.md"))
+	checkFileNotExist(t, filepath.Join(dir, "foo.md"))
+
+	dg.docCmd.SetArgs([]string{"generate-markdown", "--check=false", "--directory", dir})
+	assert.NoError(t, dg.Execute())
+
+	buf.Reset()
+	dg.docCmd.SetArgs([]string{"generate-markdown", "--check", "--directory", dir})
+	assert.NoError(t, dg.Execute())
+	assert.Empty(t, buf.String())
+}
+
+func TestDryRunFlag(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "child1", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(cmd2)
+
+	dg := CreateDocGenCmdLineTool(appCmd)
+	dg.AddDocGenerator(&CobraManOptions{}, "troff")
+	buf := new(bytes.Buffer)
+	dg.docCmd.SetOutput(buf)
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--dry-run"})
+	assert.NoError(t, dg.Execute())
+	assert.Equal(t, "foo.1\tfoo\nfoo-child1.1\tfoo child1\n", buf.String())
+	checkFileNotExist(t, "foo.1")
+	checkFileNotExist(t, "foo-child1.1")
+}
+
+func TestCommandFlag(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "remote", Short: "Manage remotes"}
+	cmd3 := &cobra.Command{Use: "add", Short: "Add a remote", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd2.AddCommand(cmd3)
+	appCmd.AddCommand(cmd2)
+
+	dg := CreateDocGenCmdLineTool(appCmd)
+	dg.AddDocGenerator(&CobraManOptions{}, "troff")
+	buf := new(bytes.Buffer)
+	dg.docCmd.SetOutput(buf)
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--command", "foo remote add"})
+	assert.NoError(t, dg.Execute())
+	assert.Regexp(t, ".SH NAME\nfoo\\\\-remote\\\\-add - Add a remote", buf.String())
+	checkFileNotExist(t, "foo-remote-add.1")
+
+	// The application name may be left off
+	buf.Reset()
+	dg.docCmd.SetArgs([]string{"generate-troff", "--command", "remote"})
+	assert.NoError(t, dg.Execute())
+	assert.Regexp(t, ".SH NAME\nfoo\\\\-remote - Manage remotes", buf.String())
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--command", "foo remote nope"})
+	assert.Error(t, dg.Execute())
+}
+
+func TestModeFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--check", "--command", "foo"},
+		{"--dry-run", "--check"},
+		{"--list", "--command", "foo"},
+		{"--archive", "docs.tar.gz", "--check"},
+		{"--dry-run", "--archive", "docs.zip"},
+	} {
+		dg := CreateDocGenCmdLineTool(&cobra.Command{Use: "foo", Run: func(cmd *cobra.Command, args []string) {}})
+		dg.AddDocGenerator(&CobraManOptions{}, "troff")
+		buf := new(bytes.Buffer)
+		dg.docCmd.SetOutput(buf)
+		dg.docCmd.SetArgs(append([]string{"generate-troff"}, args...))
+		err := dg.Execute()
+		if assert.Error(t, err, "%v", args) {
+			assert.Contains(t, err.Error(), "can't be used together")
+		}
+		assert.NotContains(t, buf.String(), ".SH NAME")
+	}
+	checkFileNotExist(t, "docs.tar.gz")
+	checkFileNotExist(t, "docs.zip")
+}
+
+func TestGzipFlag(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	dg := CreateDocGenCmdLineTool(appCmd)
+	opts := &CobraManOptions{}
+	dg.AddDocGenerator(opts, "troff")
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--gzip"})
+	assert.NoError(t, dg.Execute())
+	checkForFile(t, "foo.1.gz")
+	checkFileNotExist(t, "foo.1")
+	assert.False(t, opts.Gzip) // the options passed in are left alone
+}
+
+func TestTemplateFileFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	templateFile := filepath.Join(dir, "page.tmpl")
+	assert.NoError(t, ioutil.WriteFile(templateFile, []byte("Custom {{ .CommandPath }}"), 0644))
+
+	appCmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "child1", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(cmd2)
+	dg := CreateDocGenCmdLineTool(appCmd)
+	dg.AddDocGenerator(&CobraManOptions{}, "troff")
+
+	// Separator and extension default to those of the troff template
+	dg.docCmd.SetArgs([]string{"generate-troff", "--directory", dir, "--template-file", templateFile})
+	assert.NoError(t, dg.Execute())
+	content, err := ioutil.ReadFile(filepath.Join(dir, "foo-child1.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Custom foo child1", string(content))
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--directory", dir, "--template-file", templateFile,
+		"--template-separator", ".", "--template-extension", "txt"})
+	assert.NoError(t, dg.Execute())
+	checkForFile(t, filepath.Join(dir, "foo.child1.txt"))
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--template-file", filepath.Join(dir, "missing.tmpl")})
+	assert.Error(t, dg.Execute())
+}
+
+func TestAddTemplateLister(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	dg := CreateDocGenCmdLineTool(appCmd)
+	dg.AddTemplateLister()
+	buf := new(bytes.Buffer)
+	dg.docCmd.SetOutput(buf)
+
+	dg.docCmd.SetArgs([]string{"list-templates"})
+	assert.NoError(t, dg.Execute())
+	assert.Regexp(t, "^NAME +SEPARATOR +EXTENSION +DESCRIPTION\n", buf.String())
+	assert.Regexp(t, "\nmarkdown +\"_\" +md +Markdown page\n", buf.String())
+	assert.Regexp(t, "\ntroff +\"-\" +use_section +Man page", buf.String())
+}
+
+func TestAddConfigGenerator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobraman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	appCmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(cmd2)
+	dg := CreateDocGenCmdLineTool(appCmd)
+	dg.AddConfigGenerator(&CobraManOptions{CenterHeader: "From Go"})
+
+	// The directories in the config are relative to the working directory
+	configFile := writeConfig(t, dir, "docs.yaml", yamlConfig)
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	dg.docCmd.SetArgs([]string{"generate", "--config", configFile, "--directory", "md"})
+	assert.NoError(t, dg.Execute())
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "man", "foo-bar.8"))
+	assert.NoError(t, err)
+	assert.Regexp(t, ".TH \"FOO\\\\-BAR\" \"8\" \"Jun 1968\" \"Example 1.0\" \"From Go\"", string(content))
+	assert.Regexp(t, ".SH ENVIRONMENT\n.PP\nBAR\\\\_HOME is used", string(content))
+	checkForFile(t, filepath.Join(dir, "man", "foo.8"))
+	checkForFile(t, filepath.Join(dir, "md", "foo.md"))
+	checkForFile(t, filepath.Join(dir, "md", "foo_bar.md"))
+}
+
+func TestOptionFlags(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	dg := CreateDocGenCmdLineTool(appCmd)
+	opts := &CobraManOptions{LeftFooter: "Foo", CenterHeader: "Foo Manual", Author: "From Go"}
+	dg.AddDocGenerator(opts, "troff")
+	buf := new(bytes.Buffer)
+	dg.docCmd.SetOutput(buf)
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--command", "foo", "--section", "8",
+		"--date", "1968-06-21", "--left-footer", "Foo 1.2.3", "--author", "From the build"})
+	assert.NoError(t, dg.Execute())
+	assert.Regexp(t, ".TH \"FOO\" \"8\" \"Jun 1968\" \"Foo 1.2.3\" \"Foo Manual\"", buf.String())
+	assert.Regexp(t, ".SH AUTHOR\nFrom the build\n", buf.String())
+	assert.Equal(t, "Foo", opts.LeftFooter) // the options passed in are left alone
+
+	dg.docCmd.SetArgs([]string{"generate-troff", "--command", "foo", "--date", "June 21"})
+	assert.Error(t, dg.Execute())
+
+	of := addOptionFlags(pflag.NewFlagSet("test", pflag.ContinueOnError))
+	assert.NoError(t, of.flags.Parse([]string{"--parallel", "4", "--include", "foo bar*", "--include", "foo", "--exclude", "foo bar zap"}))
+	runOpts := CobraManOptions{}
+	assert.NoError(t, of.apply(&runOpts))
+	assert.Equal(t, 4, runOpts.Parallelism)
+	assert.Equal(t, []string{"foo bar*", "foo"}, runOpts.Include)
+	assert.Equal(t, []string{"foo bar zap"}, runOpts.Exclude)
+}
+
+func TestAddPreviewer(t *testing.T) {
+	appCmd := &cobra.Command{Use: "foo"}
+	cmd2 := &cobra.Command{Use: "bar", Short: "Bar things", Run: func(cmd *cobra.Command, args []string) {}}
+	appCmd.AddCommand(cmd2)
+	dg := CreateDocGenCmdLineTool(appCmd)
+	dg.AddPreviewer(&CobraManOptions{CenterHeader: "Foo Manual"})
+	buf := new(bytes.Buffer)
+	dg.docCmd.SetOutput(buf)
+
+	// Output that is not a terminal is plain text without a pager
+	dg.docCmd.SetArgs([]string{"preview", "foo", "bar", "--width", "50"})
+	assert.NoError(t, dg.Execute())
+	assert.Regexp(t, "^FOO-BAR\\(1\\) +Foo Manual +FOO-BAR\\(1\\)\n", buf.String())
+	assert.Contains(t, buf.String(), "NAME\n       foo-bar - Bar things\n")
+	assert.NotContains(t, buf.String(), "\b")
+
+	dg.docCmd.SetArgs([]string{"preview", "bar", "--template", "markdown2"})
+	assert.Error(t, dg.Execute())
+	dg.docCmd.SetArgs([]string{"preview", "nope", "--template", "mdoc"})
+	assert.Error(t, dg.Execute())
+}
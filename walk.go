@@ -0,0 +1,137 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+// WalkCommands calls fn for cmd and every command below it that gets a page
+// with the passed in CobraManOptions, in the order GenerateDocs generates
+// them.  Walking stops at the first error fn returns.  cmd itself is always
+// visited unless it is left out by opts.Include.
+func WalkCommands(cmd *cobra.Command, opts *CobraManOptions, fn func(cmd *cobra.Command) error) error {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad command pattern %q: %v", pattern, err)
+		}
+	}
+	return walkCommands(cmd, opts, fn)
+}
+
+func walkCommands(cmd *cobra.Command, opts *CobraManOptions, fn func(cmd *cobra.Command) error) error {
+	if opts.included(cmd) {
+		if err := fn(cmd); err != nil {
+			return err
+		}
+	}
+	for _, c := range cmd.Commands() {
+		if opts.skipped(c) {
+			continue
+		}
+		if err := walkCommands(c, opts, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasPage reports whether cmd gets a page with these options.  Commands
+// that are not available, such as hidden or deprecated ones, only do with
+// IncludeHidden or IncludeDeprecated set, and additional help topics only
+// do without ExcludeHelpTopics.  The root command always does unless it is left
+// out by Include.  This is what decides which commands are linked to in
+// SEE ALSO sections.
+func (opts *CobraManOptions) HasPage(cmd *cobra.Command) bool {
+	if !opts.included(cmd) {
+		return false
+	}
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if opts.skipped(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipped reports whether cmd and everything below it is left out.
+func (opts *CobraManOptions) skipped(cmd *cobra.Command) bool {
+	if isHelpTopic(cmd) {
+		if opts.ExcludeHelpTopics {
+			return true
+		}
+	} else if !opts.available(cmd) {
+		return true
+	}
+	if matchCommand(opts.Exclude, cmd) {
+		return true
+	}
+	return opts.Filter != nil && !opts.Filter(cmd)
+}
+
+// available is cobra's IsAvailableCommand but lets hidden and deprecated
+// commands through when they are included.
+func (opts *CobraManOptions) available(cmd *cobra.Command) bool {
+	if cmd.IsAvailableCommand() {
+		return true
+	}
+	hidden, deprecated := cmd.Hidden, len(cmd.Deprecated) != 0
+	if (!hidden && !deprecated) || (hidden && !opts.IncludeHidden) || (deprecated && !opts.IncludeDeprecated) {
+		return false
+	}
+	if cmd.Runnable() {
+		return true
+	}
+	for _, c := range cmd.Commands() {
+		if opts.available(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// pageSection returns the man section of the page for cmd.
+func (opts *CobraManOptions) pageSection(cmd *cobra.Command) string {
+	if isHelpTopic(cmd) {
+		return opts.HelpTopicSection
+	}
+	return opts.Section
+}
+
+// isHelpTopic reports whether cmd is an additional help topic.  cobra
+// considers a root command without a Run or children one as well, but it
+// still documents the app.
+func isHelpTopic(cmd *cobra.Command) bool {
+	return cmd.HasParent() && cmd.IsAdditionalHelpTopicCommand()
+}
+
+// included reports whether cmd matches Include, if it is set.
+func (opts *CobraManOptions) included(cmd *cobra.Command) bool {
+	return len(opts.Include) == 0 || matchCommand(opts.Include, cmd)
+}
+
+// matchCommand reports whether the command path of cmd matches one of the
+// patterns.  A pattern that is not valid matches nothing.
+func matchCommand(patterns []string, cmd *cobra.Command) bool {
+	cmdPath := cmd.CommandPath()
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, cmdPath); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Copyright © 2018 Ray Johnson <ray.johnson@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cobraman
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func walkTestCmd() *cobra.Command {
+	run := func(cmd *cobra.Command, args []string) {}
+	appCmd := &cobra.Command{Use: "foo"}
+	remoteCmd := &cobra.Command{Use: "remote", Run: run}
+	remoteCmd.AddCommand(&cobra.Command{Use: "add", Run: run}, &cobra.Command{Use: "remove", Run: run})
+	configCmd := &cobra.Command{Use: "config", Run: run}
+	configCmd.AddCommand(&cobra.Command{Use: "get", Run: run})
+	appCmd.AddCommand(remoteCmd, configCmd, &cobra.Command{Use: "secret", Hidden: true, Run: run})
+	return appCmd
+}
+
+func walkedPaths(t *testing.T, cmd *cobra.Command, opts *CobraManOptions) []string {
+	paths := make([]string, 0)
+	assert.NoError(t, WalkCommands(cmd, opts, func(c *cobra.Command) error {
+		paths = append(paths, c.CommandPath())
+		return nil
+	}))
+	return paths
+}
+
+func TestWalkCommands(t *testing.T) {
+	appCmd := walkTestCmd()
+	assert.Equal(t, []string{"foo", "foo config", "foo config get", "foo remote", "foo remote add", "foo remote remove"},
+		walkedPaths(t, appCmd, &CobraManOptions{}))
+
+	// Exclude and Filter leave out the children too, Include does not
+	assert.Equal(t, []string{"foo", "foo remote", "foo remote add", "foo remote remove"},
+		walkedPaths(t, appCmd, &CobraManOptions{Exclude: []string{"foo config"}}))
+	assert.Equal(t, []string{"foo", "foo config", "foo config get"},
+		walkedPaths(t, appCmd, &CobraManOptions{Filter: func(c *cobra.Command) bool { return c.Name() != "remote" }}))
+	assert.Equal(t, []string{"foo remote add", "foo remote remove"},
+		walkedPaths(t, appCmd, &CobraManOptions{Include: []string{"foo remote *"}}))
+	assert.Equal(t, []string{"foo", "foo remote", "foo remote add"},
+		walkedPaths(t, appCmd, &CobraManOptions{Include: []string{"foo", "foo remote*"}, Exclude: []string{"* remove"}}))
+
+	err := WalkCommands(appCmd, &CobraManOptions{Exclude: []string{"foo ["}}, func(c *cobra.Command) error { return nil })
+	assert.Error(t, err)
+	err = WalkCommands(appCmd, &CobraManOptions{}, func(c *cobra.Command) error { return fmt.Errorf("stop") })
+	assert.EqualError(t, err, "stop")
+}
+
+func TestHasPageInSeeAlso(t *testing.T) {
+	appCmd := walkTestCmd()
+	remoteCmd, _, _ := appCmd.Find([]string{"remote"})
+	addCmd, _, _ := appCmd.Find([]string{"remote", "add"})
+
+	opts := &CobraManOptions{Include: []string{"foo remote *"}}
+	assert.False(t, opts.HasPage(remoteCmd))
+	assert.True(t, opts.HasPage(addCmd))
+	page := BuildPage(addCmd, opts)
+	assert.Equal(t, []SeeAlso{{CmdPath: "foo remote remove", Section: "1", IsSibling: true}}, page.SeeAlsos)
+
+	opts = &CobraManOptions{Exclude: []string{"foo remote add"}}
+	page = BuildPage(remoteCmd, opts)
+	assert.Equal(t, []SeeAlso{{CmdPath: "foo remote remove", Section: "1", IsChild: true}}, page.SubCommands)
+	assert.Len(t, page.SeeAlsos, 3) // foo, foo config and foo remote remove
+
+	opts = &CobraManOptions{Exclude: []string{"foo remote"}}
+	assert.False(t, opts.HasPage(addCmd))
+	files, err := ListDocs(appCmd, opts, "", "troff")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+}